@@ -13,8 +13,15 @@ import (
 	"github.com/xmidt-org/arrange/arrangepprof"
 	"github.com/xmidt-org/candlelight"
 	"github.com/xmidt-org/sallust"
+	"github.com/xmidt-org/skeleton/internal/accesslog"
 	"github.com/xmidt-org/skeleton/internal/apiauth"
+	"github.com/xmidt-org/skeleton/internal/cors"
+	"github.com/xmidt-org/skeleton/internal/credentials"
+	"github.com/xmidt-org/skeleton/internal/health"
 	"github.com/xmidt-org/skeleton/internal/oker"
+	"github.com/xmidt-org/skeleton/internal/requestid"
+	"github.com/xmidt-org/skeleton/internal/routes"
+	"github.com/xmidt-org/skeleton/internal/shutdown"
 	"github.com/xmidt-org/touchstone"
 	"github.com/xmidt-org/touchstone/touchhttp"
 	"gopkg.in/dealancer/validate.v2"
@@ -28,8 +35,13 @@ type Config struct {
 	Prometheus        touchstone.Config
 	PrometheusHandler touchhttp.Config
 	Servers           Servers
-	Routes            Routes
+	AccessLog         accesslog.Config
+	Health            health.Config
+	RequestID         requestid.Config
+	Shutdown          shutdown.Config
+	Routes            routes.Config
 	Auth              apiauth.Config
+	Credentials       credentials.Config
 	Oker              oker.Config
 }
 
@@ -41,6 +53,9 @@ type Servers struct {
 	Alternate PrimaryServer
 }
 
+// HealthServer serves the readiness probe on Path as well as the /livez,
+// /readyz and /startupz probes.  Add the verbose query parameter for the full
+// report.
 type HealthServer struct {
 	HTTP arrangehttp.ServerConfig
 	Path HealthPath //`validate:"empty=false"`
@@ -55,25 +70,29 @@ type MetricsServer struct {
 
 type MetricsPath string
 
+// PrimaryServer serves the routes.  CORS is the policy of its routes, which
+// each route can replace.
 type PrimaryServer struct {
 	HTTP arrangehttp.ServerConfig
+	CORS cors.Config
 }
 
 type PprofServer struct {
-	HTTP arrangehttp.ServerConfig
-	Path PprofPathPrefix
+	HTTP         arrangehttp.ServerConfig
+	Path         PprofPathPrefix
+	RecentEvents RecentEventsPath
+	EventStream  EventStreamPath
 }
 
 type PprofPathPrefix string
 
-type Routes struct {
-	Oker Route
-}
+// RecentEventsPath is where the recent oker events can be queried on the
+// pprof server.
+type RecentEventsPath string
 
-type Route struct {
-	Path   string `validate:"empty=false"`
-	Server string `validate:"one_of=primary,alternate"`
-}
+// EventStreamPath is where the live stream of oker events is served on the
+// pprof server.
+type EventStreamPath string
 
 // Collect and process the configuration files and env vars and
 // produce a configuration object.
@@ -157,7 +176,9 @@ var defaultConfig = Config{
 				Network: "tcp",
 				Address: "127.0.0.1:9999",
 			},
-			Path: arrangepprof.DefaultPathPrefix,
+			Path:         arrangepprof.DefaultPathPrefix,
+			RecentEvents: RecentEventsPath("/debug/oker/events"),
+			EventStream:  EventStreamPath("/debug/oker/events/stream"),
 		},
 		Primary: PrimaryServer{
 			HTTP: arrangehttp.ServerConfig{
@@ -172,10 +193,39 @@ var defaultConfig = Config{
 			},
 		},
 	},
-	Routes: Routes{
-		Oker: Route{
-			Path:   "/api/ok",
-			Server: "primary",
+	AccessLog: accesslog.Config{
+		ExcludeServers: []string{"health", "metrics"},
+	},
+	Health: health.Config{
+		Timeout: 5 * time.Second,
+	},
+	RequestID: requestid.Config{
+		Headers: []string{requestid.DefaultHeader, requestid.MidtHeader},
+		// Only local callers are trusted.  The proxies in front of the
+		// service have to be listed.
+		Trusted: []string{
+			"127.0.0.0/8",
+			"::1/128",
+		},
+		MaxLength: requestid.DefaultMaxLength,
+	},
+	Shutdown: shutdown.Config{
+		Drain:   5 * time.Second,
+		Timeout: 10 * time.Second,
+	},
+	Routes: routes.Config{
+		"oker": routes.Route{
+			Path:    "/api/ok",
+			Servers: []string{"primary"},
+		},
+	},
+	Oker: oker.Config{
+		Name: applicationName,
+		Recent: oker.Recent{
+			Capacity: 1000,
+		},
+		Stream: oker.Stream{
+			BufferSize: 100,
 		},
 	},
 	Prometheus: touchstone.Config{
@@ -0,0 +1,192 @@
+// SPDX-FileCopyrightText: 2024 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+// Package accesslog logs a line for every request the http servers handle,
+// using the field names in the internal/log package.
+package accesslog
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"path"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/xmidt-org/sallust"
+	"github.com/xmidt-org/skeleton/internal/log"
+	"github.com/xmidt-org/skeleton/internal/middleware"
+	"go.uber.org/zap"
+)
+
+// Message is the message of the access log lines.
+const Message = "http request"
+
+// Config is the configuration of the access log.
+type Config struct {
+	// Disable turns the access log off.
+	Disable bool
+
+	// Sample logs one in every Sample requests.  Zero or one logs every
+	// request.  Server errors are always logged.
+	Sample int
+
+	// Exclude are the path patterns of the requests that are not logged.
+	// The patterns use the path.Match syntax, for example /debug/pprof/*.
+	Exclude []string
+
+	// ExcludeServers are the servers whose requests are not logged.
+	ExcludeServers []string
+
+	// BodyMaxSize is the most bytes of the request body that are logged.
+	// Only what the handler reads is logged.  Zero leaves the body out.
+	BodyMaxSize int
+}
+
+// Logger logs the requests.
+type Logger struct {
+	config Config
+	logger *zap.Logger
+	count  atomic.Uint64
+}
+
+// New creates the access logger.  Without a logger nothing is logged unless
+// the request context has one.
+func New(opts ...Option) (*Logger, error) {
+	var l Logger
+
+	for _, opt := range opts {
+		if opt != nil {
+			if err := opt.apply(&l); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if l.logger == nil {
+		l.logger = zap.NewNop()
+	}
+
+	return &l, nil
+}
+
+// Middleware logs the requests to the named server.  The request-scoped
+// logger is used when there is one, so the lines carry its trace and request
+// ids.
+func (l *Logger) Middleware(server string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if l.config.Disable || slices.Contains(l.config.ExcludeServers, server) {
+			return next
+		}
+
+		return http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+			if l.excluded(req.URL.Path) {
+				next.ServeHTTP(resp, req)
+				return
+			}
+
+			start := time.Now()
+
+			var extra fields
+			ctx := context.WithValue(req.Context(), fieldsKey{}, &extra)
+
+			var body *capture
+			if l.config.BodyMaxSize > 0 && req.Body != nil && req.Body != http.NoBody {
+				body = &capture{body: req.Body, max: l.config.BodyMaxSize}
+				req.Body = body
+			}
+
+			req, route := middleware.TrackRoute(req.WithContext(ctx))
+			rec := middleware.NewRecorder(resp)
+			next.ServeHTTP(rec, req)
+
+			if rec.Code < http.StatusInternalServerError && !l.sampled() {
+				return
+			}
+
+			fs := []zap.Field{
+				zap.String(log.ServerName, server),
+				zap.String(log.HTTPRequestMethod, req.Method),
+				zap.String(log.URLPath, req.URL.Path),
+				zap.String(log.HTTPRoute, route.Pattern()),
+				zap.Int(log.HTTPResponseStatusCode, rec.Code),
+				zap.Int64(log.HTTPResponseBodyBytes, rec.Bytes),
+				zap.Duration(log.EventDuration, time.Since(start)),
+				zap.String(log.ClientAddress, req.RemoteAddr),
+				zap.String(log.UserAgent, req.UserAgent()),
+			}
+			if body != nil {
+				fs = append(fs, zap.ByteString(log.RequestBody, body.buf))
+			}
+			fs = append(fs, extra.get()...)
+
+			sallust.GetDefault(ctx, l.logger).Info(Message, fs...)
+		})
+	}
+}
+
+func (l *Logger) excluded(p string) bool {
+	for _, pattern := range l.config.Exclude {
+		if ok, _ := path.Match(pattern, p); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func (l *Logger) sampled() bool {
+	if l.config.Sample <= 1 {
+		return true
+	}
+	return (l.count.Add(1)-1)%uint64(l.config.Sample) == 0 // nolint: gosec
+}
+
+type fieldsKey struct{}
+
+// fields are added to the access log line by the handlers.
+type fields struct {
+	lock sync.Mutex
+	f    []zap.Field
+}
+
+func (f *fields) add(fs ...zap.Field) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.f = append(f.f, fs...)
+}
+
+func (f *fields) get() []zap.Field {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return f.f
+}
+
+// Add adds fields to the access log line of the request, such as the
+// principal once it is authenticated.  It does nothing if the request is not
+// logged.
+func Add(ctx context.Context, fs ...zap.Field) {
+	if f, ok := ctx.Value(fieldsKey{}).(*fields); ok {
+		f.add(fs...)
+	}
+}
+
+// capture keeps the start of the request body as it is read.
+type capture struct {
+	body io.ReadCloser
+	max  int
+	buf  []byte
+}
+
+func (c *capture) Read(p []byte) (int, error) {
+	n, err := c.body.Read(p)
+	if room := c.max - len(c.buf); room > 0 {
+		c.buf = append(c.buf, p[:min(n, room)]...)
+	}
+	return n, err
+}
+
+func (c *capture) Close() error {
+	return c.body.Close()
+}
@@ -0,0 +1,144 @@
+// SPDX-FileCopyrightText: 2024 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package accesslog
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xmidt-org/sallust"
+	"github.com/xmidt-org/skeleton/internal/log"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func newLogger(t *testing.T, c Config) (*Logger, *observer.ObservedLogs) {
+	core, logs := observer.New(zap.InfoLevel)
+	l, err := New(WithConfig(c), WithLogger(zap.New(core)))
+	require.NoError(t, err)
+	return l, logs
+}
+
+func TestMiddleware(t *testing.T) {
+	l, logs := newLogger(t, Config{BodyMaxSize: 4})
+
+	mux := chi.NewMux()
+	mux.Post("/api/{version}/ok", func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		assert.Equal(t, "some body", string(b))
+		Add(r.Context(), zap.String(log.UserName, "joe"))
+		w.WriteHeader(http.StatusAccepted)
+		_, _ = io.WriteString(w, "hello")
+	})
+	h := l.Middleware("primary")(mux)
+
+	req := httptest.NewRequest("POST", "/api/v1/ok", strings.NewReader("some body"))
+	req.Header.Set("User-Agent", "tester")
+	req.RemoteAddr = "10.0.0.1:1234"
+	resp := httptest.NewRecorder()
+	h.ServeHTTP(resp, req)
+	assert.Equal(t, http.StatusAccepted, resp.Code)
+
+	entries := logs.FilterMessage(Message).All()
+	require.Len(t, entries, 1)
+
+	fields := entries[0].ContextMap()
+	assert.Equal(t, "primary", fields[log.ServerName])
+	assert.Equal(t, "POST", fields[log.HTTPRequestMethod])
+	assert.Equal(t, "/api/v1/ok", fields[log.URLPath])
+	assert.Equal(t, "/api/{version}/ok", fields[log.HTTPRoute])
+	assert.Equal(t, int64(http.StatusAccepted), fields[log.HTTPResponseStatusCode])
+	assert.Equal(t, int64(5), fields[log.HTTPResponseBodyBytes])
+	assert.Contains(t, fields, log.EventDuration)
+	assert.Equal(t, "10.0.0.1:1234", fields[log.ClientAddress])
+	assert.Equal(t, "tester", fields[log.UserAgent])
+	assert.Equal(t, "some", fields[log.RequestBody])
+	assert.Equal(t, "joe", fields[log.UserName])
+}
+
+func TestMiddlewareRequestLogger(t *testing.T) {
+	l, logs := newLogger(t, Config{})
+	core, scoped := observer.New(zap.InfoLevel)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /debug/{name}", func(http.ResponseWriter, *http.Request) {})
+	h := l.Middleware("pprof")(mux)
+
+	req := httptest.NewRequest("GET", "/debug/heap", nil)
+	req = req.WithContext(sallust.With(req.Context(), zap.New(core).With(zap.String(log.TraceID, "trace"))))
+	h.ServeHTTP(httptest.NewRecorder(), req)
+
+	assert.Zero(t, logs.Len())
+	entries := scoped.FilterMessage(Message).All()
+	require.Len(t, entries, 1)
+	assert.Equal(t, "trace", entries[0].ContextMap()[log.TraceID])
+	assert.Equal(t, "GET /debug/{name}", entries[0].ContextMap()[log.HTTPRoute])
+	assert.NotContains(t, entries[0].ContextMap(), log.RequestBody)
+}
+
+func TestMiddlewareExclude(t *testing.T) {
+	l, logs := newLogger(t, Config{
+		Exclude:        []string{"/debug/*"},
+		ExcludeServers: []string{"health"},
+	})
+
+	ok := http.HandlerFunc(func(http.ResponseWriter, *http.Request) {})
+	l.Middleware("health")(ok).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/readyz", nil))
+	l.Middleware("pprof")(ok).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/debug/heap", nil))
+	assert.Zero(t, logs.Len())
+
+	l.Middleware("pprof")(ok).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/other", nil))
+	assert.Equal(t, 1, logs.Len())
+}
+
+func TestMiddlewareSample(t *testing.T) {
+	l, logs := newLogger(t, Config{Sample: 3})
+
+	code := http.StatusOK
+	h := l.Middleware("primary")(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(code)
+	}))
+
+	for range 6 {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	}
+	assert.Equal(t, 2, logs.Len())
+
+	// Server errors are always logged.
+	code = http.StatusInternalServerError
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	assert.Equal(t, 4, logs.Len())
+}
+
+func TestMiddlewareDisabled(t *testing.T) {
+	l, logs := newLogger(t, Config{Disable: true})
+
+	l.Middleware("primary")(http.NotFoundHandler()).ServeHTTP(
+		httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil),
+	)
+	assert.Zero(t, logs.Len())
+
+	// Add is harmless when the request isn't logged.
+	Add(httptest.NewRequest("GET", "/", nil).Context(), zap.String("k", "v"))
+}
+
+func TestInvalidConfig(t *testing.T) {
+	tests := []Config{
+		{Sample: -1},
+		{BodyMaxSize: -1},
+		{Exclude: []string{"["}},
+	}
+
+	for _, c := range tests {
+		_, err := New(WithConfig(c))
+		assert.ErrorIs(t, err, ErrInvalidConfig)
+	}
+}
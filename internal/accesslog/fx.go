@@ -0,0 +1,27 @@
+// SPDX-FileCopyrightText: 2024 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package accesslog
+
+import (
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+)
+
+type LoggerIn struct {
+	fx.In
+	Config Config `optional:"true"`
+	Logger *zap.Logger
+}
+
+// Module provides the access *Logger.
+var Module = fx.Module("accesslog",
+	fx.Provide(
+		func(in LoggerIn) (*Logger, error) {
+			return New(
+				WithConfig(in.Config),
+				WithLogger(in.Logger),
+			)
+		},
+	),
+)
@@ -0,0 +1,50 @@
+// SPDX-FileCopyrightText: 2024 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package accesslog
+
+import (
+	"errors"
+	"fmt"
+	"path"
+
+	"go.uber.org/zap"
+)
+
+var ErrInvalidConfig = errors.New("invalid access log configuration")
+
+type Option interface {
+	apply(*Logger) error
+}
+
+type optionFunc func(*Logger) error
+
+func (f optionFunc) apply(l *Logger) error {
+	return f(l)
+}
+
+// WithConfig sets the configuration.  The sizes can't be negative and the
+// exclusions must be valid patterns.
+func WithConfig(c Config) Option {
+	return optionFunc(func(l *Logger) error {
+		if c.Sample < 0 || c.BodyMaxSize < 0 {
+			return fmt.Errorf("%w: negative sample or body size", ErrInvalidConfig)
+		}
+		for _, pattern := range c.Exclude {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return fmt.Errorf("%w: exclude '%s': %w", ErrInvalidConfig, pattern, err)
+			}
+		}
+
+		l.config = c
+		return nil
+	})
+}
+
+// WithLogger sets the logger used when the request context has none.
+func WithLogger(logger *zap.Logger) Option {
+	return optionFunc(func(l *Logger) error {
+		l.logger = logger
+		return nil
+	})
+}
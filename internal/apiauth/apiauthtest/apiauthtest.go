@@ -0,0 +1,321 @@
+// SPDX-FileCopyrightText: 2024 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+// Package apiauthtest provides an in-memory JWKS server and token factory for
+// tests that need to exercise the apiauth package.
+package apiauthtest
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"time"
+
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/lestrrat-go/jwx/v2/jwt"
+	"github.com/xmidt-org/skeleton/internal/apiauth"
+)
+
+// KeyType is the type of key the server signs tokens with.
+type KeyType string
+
+const (
+	RSA     KeyType = "rsa"
+	EC256   KeyType = "ec256"
+	EC384   KeyType = "ec384"
+	EC521   KeyType = "ec521"
+	Ed25519 KeyType = "ed25519"
+)
+
+// KeyTypes lists all the supported key types.
+var KeyTypes = []KeyType{RSA, EC256, EC384, EC521, Ed25519}
+
+// The refresh interval used by Config.  The jwk cache does not allow a shorter
+// interval.
+const refreshInterval = 15 * time.Minute
+
+// Claims are arbitrary claims to include in a minted token.
+type Claims map[string]any
+
+// Server is an httptest based JWKS server that mints tokens that can be
+// verified using the keys it serves.
+//
+// The server always publishes the previous, current and next keys.  Tokens are
+// signed with the current key.  Publishing the next key before it is used
+// allows a single Rotate() to be picked up by a key set that was fetched
+// before the rotation, just like a real key provider.
+type Server struct {
+	keyType          KeyType
+	publishAlgorithm bool
+
+	m       sync.Mutex
+	server  *httptest.Server
+	keys    []jwk.Key // previous (optional), current, next
+	serial  int
+	fetches int
+}
+
+// Option is an interface that is used to apply options to the Server.
+type Option interface {
+	apply(*Server) error
+}
+
+type optionFunc func(*Server) error
+
+func (f optionFunc) apply(s *Server) error {
+	return f(s)
+}
+
+// WithKeyType sets the type of key used to sign tokens.  The default is RSA.
+func WithKeyType(kt KeyType) Option {
+	return optionFunc(func(s *Server) error {
+		for _, known := range KeyTypes {
+			if kt == known {
+				s.keyType = kt
+				return nil
+			}
+		}
+		return fmt.Errorf("unknown key type '%s'", kt)
+	})
+}
+
+// WithPublishedAlgorithm includes the "alg" field in the published keys.  By
+// default the field is omitted so the algorithm mapping done by apiauth is
+// exercised.
+func WithPublishedAlgorithm() Option {
+	return optionFunc(func(s *Server) error {
+		s.publishAlgorithm = true
+		return nil
+	})
+}
+
+// New creates and starts a new Server.  Close must be called when the server
+// is no longer needed.
+func New(opts ...Option) (*Server, error) {
+	s := Server{
+		keyType: RSA,
+	}
+
+	for _, opt := range opts {
+		if opt != nil {
+			if err := opt.apply(&s); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	for range 2 {
+		key, err := s.generate()
+		if err != nil {
+			return nil, err
+		}
+		s.keys = append(s.keys, key)
+	}
+
+	s.server = httptest.NewServer(http.HandlerFunc(s.serveKeys))
+
+	return &s, nil
+}
+
+// Close shuts down the server.
+func (s *Server) Close() {
+	s.server.Close()
+}
+
+// URL returns the URL of the JWKS endpoint.
+func (s *Server) URL() string {
+	return s.server.URL
+}
+
+// Fetches returns the number of times the key set has been fetched.
+func (s *Server) Fetches() int {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	return s.fetches
+}
+
+// Config returns an apiauth.Config that uses this server as the key provider.
+// Any capabilities provided are required by the returned configuration.
+func (s *Server) Config(requiredCapabilities ...string) apiauth.Config {
+	return apiauth.Config{
+		JWT: apiauth.JWT{
+			KeyProvider: apiauth.Provider{
+				URL:             s.URL(),
+				RefreshInterval: refreshInterval,
+			},
+			RequiredServiceCapabilities: requiredCapabilities,
+		},
+	}
+}
+
+// Rotate makes the next key the signing key, publishes a new next key and
+// retires the oldest key.
+func (s *Server) Rotate() error {
+	key, err := s.generate()
+	if err != nil {
+		return err
+	}
+
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	s.keys = append(s.keys, key)
+	if len(s.keys) > 3 {
+		s.keys = s.keys[len(s.keys)-3:]
+	}
+
+	return nil
+}
+
+// KeyID returns the key id of the current signing key.
+func (s *Server) KeyID() string {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	return s.current().KeyID()
+}
+
+// Mint creates a signed token with the specified claims.  Reasonable defaults
+// are provided for the registered claims (iss, sub, iat, nbf, exp, jti) and
+// can be replaced by including them in the claims.
+func (s *Server) Mint(claims Claims) (string, error) {
+	now := time.Now().Truncate(time.Second)
+
+	s.m.Lock()
+	s.serial++
+	jti := fmt.Sprintf("apiauthtest-%d", s.serial)
+	key := s.current()
+	s.m.Unlock()
+
+	b := jwt.NewBuilder().
+		Issuer("apiauthtest").
+		Subject("test-subject").
+		IssuedAt(now).
+		NotBefore(now.Add(-time.Minute)).
+		Expiration(now.Add(time.Hour)).
+		JwtID(jti)
+
+	for k, v := range claims {
+		b = b.Claim(k, v)
+	}
+
+	token, err := b.Build()
+	if err != nil {
+		return "", err
+	}
+
+	signed, err := jwt.Sign(token, jwt.WithKey(key.Algorithm(), key))
+	if err != nil {
+		return "", err
+	}
+
+	return string(signed), nil
+}
+
+// MintWithCapabilities creates a signed token for the subject that contains
+// the specified capabilities.
+func (s *Server) MintWithCapabilities(subject string, capabilities ...string) (string, error) {
+	if capabilities == nil {
+		capabilities = []string{}
+	}
+
+	return s.Mint(Claims{
+		jwt.SubjectKey: subject,
+		"capabilities": capabilities,
+	})
+}
+
+// current returns the signing key.  The lock must be held.
+func (s *Server) current() jwk.Key {
+	return s.keys[len(s.keys)-2]
+}
+
+func (s *Server) serveKeys(w http.ResponseWriter, _ *http.Request) {
+	s.m.Lock()
+	s.fetches++
+	keys := s.keys
+	s.m.Unlock()
+
+	set := jwk.NewSet()
+	for _, key := range keys {
+		pub, err := key.PublicKey()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		if !s.publishAlgorithm {
+			_ = pub.Remove(jwk.AlgorithmKey)
+		}
+
+		if err = set.AddKey(pub); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	buf, err := json.Marshal(set)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(buf)
+}
+
+// generate creates a new private key of the configured type.
+func (s *Server) generate() (jwk.Key, error) {
+	var raw any
+	var alg jwa.SignatureAlgorithm
+	var err error
+
+	switch s.keyType {
+	case RSA:
+		raw, err = rsa.GenerateKey(rand.Reader, 2048)
+		alg = jwa.RS256
+	case EC256:
+		raw, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		alg = jwa.ES256
+	case EC384:
+		raw, err = ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+		alg = jwa.ES384
+	case EC521:
+		raw, err = ecdsa.GenerateKey(elliptic.P521(), rand.Reader)
+		alg = jwa.ES512
+	case Ed25519:
+		_, raw, err = ed25519.GenerateKey(rand.Reader)
+		alg = jwa.EdDSA
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := jwk.FromRaw(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	s.m.Lock()
+	s.serial++
+	kid := fmt.Sprintf("%s-%d", s.keyType, s.serial)
+	s.m.Unlock()
+
+	if err = key.Set(jwk.KeyIDKey, kid); err != nil {
+		return nil, err
+	}
+	if err = key.Set(jwk.AlgorithmKey, alg); err != nil {
+		return nil, err
+	}
+
+	return key, nil
+}
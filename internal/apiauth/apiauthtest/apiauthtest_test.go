@@ -0,0 +1,106 @@
+// SPDX-FileCopyrightText: 2024 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package apiauthtest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xmidt-org/bascule"
+	"github.com/xmidt-org/skeleton/internal/apiauth"
+)
+
+func request(h http.Handler, token string) int {
+	req := httptest.NewRequest("GET", "/", nil)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp := httptest.NewRecorder()
+	h.ServeHTTP(resp, req)
+	return resp.Code
+}
+
+func TestServer(t *testing.T) {
+	tests := []struct {
+		keyType   KeyType
+		published bool
+	}{
+		{keyType: RSA},
+		{keyType: EC256},
+		{keyType: EC384},
+		{keyType: EC521},
+		{keyType: Ed25519},
+		{keyType: RSA, published: true},
+		{keyType: Ed25519, published: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(string(tc.keyType), func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			opts := []Option{WithKeyType(tc.keyType)}
+			if tc.published {
+				opts = append(opts, WithPublishedAlgorithm())
+			}
+
+			s, err := New(opts...)
+			require.NoError(err)
+			require.NotNil(s)
+			defer s.Close()
+
+			auth, err := apiauth.New(apiauth.WithConfig(s.Config("needed")))
+			require.NoError(err)
+			require.True(auth.Protected())
+
+			var principal string
+			h := auth.Then(func(_ http.ResponseWriter, r *http.Request) {
+				token, _ := bascule.GetFrom(r)
+				principal = token.Principal()
+			})
+
+			good, err := s.MintWithCapabilities("good-subject", "other", "needed")
+			require.NoError(err)
+			assert.Equal(http.StatusOK, request(h, good))
+			assert.Equal("good-subject", principal)
+
+			missing, err := s.MintWithCapabilities("missing-subject", "other")
+			require.NoError(err)
+			assert.Equal(http.StatusForbidden, request(h, missing))
+
+			custom, err := s.Mint(Claims{
+				"sub":          "custom-subject",
+				"capabilities": []string{"needed"},
+				"partner":      "comcast",
+			})
+			require.NoError(err)
+			assert.Equal(http.StatusOK, request(h, custom))
+			assert.Equal("custom-subject", principal)
+
+			// The key set was fetched before the rotation, but the new signing
+			// key was already published.
+			before := s.KeyID()
+			require.NoError(s.Rotate())
+			assert.NotEqual(before, s.KeyID())
+
+			rotated, err := s.MintWithCapabilities("rotated-subject", "needed")
+			require.NoError(err)
+			assert.Equal(http.StatusOK, request(h, rotated))
+			assert.Equal("rotated-subject", principal)
+			assert.Equal(1, s.Fetches())
+
+			// Tokens signed by the previous key are still accepted.
+			assert.Equal(http.StatusOK, request(h, good))
+		})
+	}
+}
+
+func TestInvalidKeyType(t *testing.T) {
+	s, err := New(WithKeyType("invalid"))
+	assert.Error(t, err)
+	assert.Nil(t, s)
+}
@@ -10,12 +10,19 @@ import (
 	"net/http"
 	"time"
 
+	kit "github.com/go-kit/kit/metrics"
 	"github.com/lestrrat-go/jwx/v2/jwk"
 	"github.com/lestrrat-go/jwx/v2/jwt"
 	"github.com/xmidt-org/arrange/arrangehttp"
 	"github.com/xmidt-org/bascule"
 	"github.com/xmidt-org/bascule/basculehttp"
 	"github.com/xmidt-org/bascule/basculejwt"
+	"github.com/xmidt-org/sallust"
+	"github.com/xmidt-org/skeleton/internal/accesslog"
+	"github.com/xmidt-org/skeleton/internal/log"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
 )
 
 // Config is a struct that holds the configuration for the Auth middleware.
@@ -42,6 +49,10 @@ type JWT struct {
 	// to be present to accept the token.  If any one of these capabilities are
 	// present will allow the token to be accepted.
 	RequiredServiceCapabilities []string
+
+	// Decryption holds the optional configuration for accepting encrypted
+	// (JWE) tokens.
+	Decryption Decryption
 }
 
 // Provider contains the configuration for accessing the public keys for JWT
@@ -62,6 +73,11 @@ type Provider struct {
 	// that are missing the "alg" field.  Sometimes the public key provider does
 	// not provide the "alg" field, and this function can be used to add it.
 	DisableAutoAddMissingAlgorithm bool
+
+	// MaxAge is how old the public keys can get before the health check
+	// fetches them again, and fails if that fetch fails.  Defaults to three
+	// refresh intervals, or an hour when no refresh interval is set.
+	MaxAge time.Duration
 }
 
 // Basic is a map of usernames to passwords.
@@ -69,8 +85,13 @@ type Basic map[string]string
 
 // Auth is a struct that holds the auth middleware.
 type Auth struct {
-	middleware *basculehttp.Middleware
-	config     Config
+	middleware     *basculehttp.Middleware
+	keys           *keySet
+	config         Config
+	parsers        []Parser
+	validators     []Validator
+	logger         *zap.Logger
+	reloadFailures kit.Counter
 }
 
 // New creates a new Auth middleware.
@@ -88,109 +109,186 @@ func New(opts ...Option) (*Auth, error) {
 		}
 	}
 
+	if auth.config.Disable {
+		return &auth, nil
+	}
+
 	ctx := context.Background()
 
+	var parser *Parser
+	var validator *Validator
+
 	if auth.config.Basic != nil {
-		auth.middleware, err = auth.config.Basic.middleware()
+		parser, validator, err = auth.config.Basic.authenticator()
 		if err != nil {
 			return nil, errors.Join(err, fmt.Errorf("error creating basic auth middleware"))
 		}
 	}
 
 	if auth.config.JWT.KeyProvider.URL != "" {
-		auth.middleware, err = auth.config.JWT.middleware(ctx)
+		parser, validator, auth.keys, err = auth.config.JWT.authenticator(ctx, auth.logger, auth.reloadFailures)
 		if err != nil {
 			return nil, errors.Join(err, fmt.Errorf("error creating jwt middleware"))
 		}
 	}
 
-	return &auth, err
+	if parser == nil && len(auth.parsers) == 0 {
+		return &auth, nil
+	}
+
+	auth.middleware, err = basculehttp.NewMiddleware(
+		basculehttp.WithErrorStatusCoder(logFailure),
+		basculehttp.UseAuthenticator(
+			basculehttp.NewAuthenticator(
+				bascule.WithTokenParsers(
+					toTokenParsers(sortOrdered(parser, auth.parsers))...,
+				),
+				bascule.WithValidators(
+					toValidators(sortOrdered(validator, auth.validators))...,
+				),
+			),
+		),
+	)
+	if err != nil {
+		return nil, errors.Join(err, fmt.Errorf("error creating auth middleware"))
+	}
+
+	return &auth, nil
 }
 
 func (auth *Auth) Protected() bool {
 	return auth.middleware != nil
 }
 
+// logFailure logs why the request was rejected with the request-scoped logger
+// and uses the default status code.
+func logFailure(req *http.Request, err error) int {
+	code := basculehttp.DefaultErrorStatusCoder(req, err)
+	sallust.Get(req.Context()).Info("request rejected by auth",
+		zap.Int("status_code", code),
+		zap.String(log.ErrorMessage, err.Error()),
+	)
+	return code
+}
+
+// The attributes Then adds to the request's span.
+const (
+	AuthOutcomeKey = attribute.Key("auth.outcome")
+	PartnerIDsKey  = attribute.Key("auth.partner_ids")
+)
+
+// Then protects the handler.  The outcome of the authentication and the
+// partner ids of the token are added to the request's span, and the principal
+// to its access log line.
 func (auth *Auth) Then(h http.HandlerFunc) http.Handler {
 	if auth.middleware == nil {
 		return h
 	}
 
-	return auth.middleware.ThenFunc(h)
+	next := auth.middleware.ThenFunc(func(resp http.ResponseWriter, req *http.Request) {
+		trace.SpanFromContext(req.Context()).SetAttributes(
+			AuthOutcomeKey.String("success"),
+			PartnerIDsKey.StringSlice(PartnerIDs(req.Context())),
+		)
+		if token, ok := bascule.Get(req.Context()); ok {
+			accesslog.Add(req.Context(), zap.String(log.UserName, token.Principal()))
+		}
+		h(resp, req)
+	})
+
+	return http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		// Replaced when the handler is reached.
+		trace.SpanFromContext(req.Context()).SetAttributes(AuthOutcomeKey.String("failure"))
+		next.ServeHTTP(resp, req)
+	})
 }
 
+// valid checks basic tokens against the configured users.  Other token types
+// come from contributed parsers and are left to their validators.
 func (cfg *Basic) valid(token bascule.Token) error {
-	if basic, ok := token.(basculehttp.BasicToken); ok {
-		for u, p := range *cfg {
-			if basic.UserName() == u && basic.Password() == p {
-				return nil
-			}
+	basic, ok := token.(basculehttp.BasicToken)
+	if !ok {
+		return nil
+	}
+
+	for u, p := range *cfg {
+		if basic.UserName() == u && basic.Password() == p {
+			return nil
 		}
 	}
 
 	return bascule.ErrBadCredentials
 }
 
-func (cfg *Basic) middleware() (*basculehttp.Middleware, error) {
+func (cfg *Basic) authenticator() (*Parser, *Validator, error) {
 	tp, err := basculehttp.NewAuthorizationParser(
 		basculehttp.WithBasic(),
 	)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
-	m, err := basculehttp.NewMiddleware(
-		basculehttp.UseAuthenticator(
-
-			basculehttp.NewAuthenticator(
-				bascule.WithTokenParsers(tp),
-				bascule.WithValidators(
-					bascule.AsValidator[*http.Request](cfg.valid),
-				),
-			),
-		),
-	)
+	parser := Parser{
+		Name:   "basic",
+		Parser: tp,
+	}
+	validator := Validator{
+		Name:      "basic",
+		Validator: bascule.AsValidator[*http.Request](cfg.valid),
+	}
 
-	return m, err
+	return &parser, &validator, nil
 }
 
-func (cfg *JWT) middleware(ctx context.Context) (*basculehttp.Middleware, error) {
+func (cfg *JWT) authenticator(ctx context.Context, logger *zap.Logger, reloadFailures kit.Counter) (*Parser, *Validator, *keySet, error) {
 	keys, err := cfg.KeyProvider.toKeySet(ctx)
 	if err != nil {
-		return nil, errors.Join(err, fmt.Errorf("error getting public keys"))
+		return nil, nil, nil, errors.Join(err, fmt.Errorf("error getting public keys"))
 	}
 
-	jwtp, err := basculejwt.NewTokenParser(jwt.WithKeySet(keys))
+	jwtp, err := basculejwt.NewTokenParser(jwt.WithKeySet(keys.set))
 	if err != nil {
-		return nil, errors.Join(err, fmt.Errorf("error creating token parser"))
+		return nil, nil, nil, errors.Join(err, fmt.Errorf("error creating token parser"))
+	}
+
+	if cfg.Decryption.enabled() {
+		d, err := newDecrypter(cfg.Decryption, logger, reloadFailures)
+		if err != nil {
+			return nil, nil, nil, errors.Join(err, fmt.Errorf("error loading decryption keys"))
+		}
+
+		jwtp = &jweTokenParser{
+			decrypter: d,
+			required:  cfg.Decryption.Required,
+			next:      jwtp,
+		}
 	}
 
 	tp, err := basculehttp.NewAuthorizationParser(
 		basculehttp.WithScheme(basculehttp.SchemeBearer, jwtp),
 	)
 	if err != nil {
-		return nil, errors.Join(err, fmt.Errorf("error creating authorization parser"))
+		return nil, nil, nil, errors.Join(err, fmt.Errorf("error creating authorization parser"))
 	}
 
-	m, err := basculehttp.NewMiddleware(
-		basculehttp.UseAuthenticator(
-			basculehttp.NewAuthenticator(
-				bascule.WithTokenParsers(tp),
-				bascule.WithValidators(
-					bascule.AsValidator[*http.Request](cfg.valid),
-				),
-			),
-		),
-	)
+	parser := Parser{
+		Name:   "jwt",
+		Parser: tp,
+	}
+	validator := Validator{
+		Name:      "jwt",
+		Validator: bascule.AsValidator[*http.Request](cfg.valid),
+	}
 
-	return m, err
+	return &parser, &validator, keys, nil
 }
 
-// just checking for at least one capabiilty
+// just checking for at least one capabiilty.  Non-JWT tokens come from
+// contributed parsers and are left to their validators.
 func (cfg *JWT) valid(token bascule.Token) error {
 	_, ok := token.(basculejwt.Claims)
 	if !ok {
-		return bascule.ErrBadCredentials
+		return nil
 	}
 
 	if len(cfg.RequiredServiceCapabilities) == 0 {
@@ -209,15 +307,27 @@ func (cfg *JWT) valid(token bascule.Token) error {
 	return bascule.ErrUnauthorized
 }
 
-func (cfg *Provider) toKeySet(ctx context.Context) (jwk.Set, error) {
-	cache := jwk.NewCache(ctx)
+func (cfg *Provider) toKeySet(ctx context.Context) (*keySet, error) {
+	keys := keySet{
+		cache:  jwk.NewCache(ctx),
+		url:    cfg.URL,
+		maxAge: cfg.MaxAge,
+	}
 
-	opts := []jwk.RegisterOption{
-		jwk.WithRefreshInterval(cfg.RefreshInterval),
+	if keys.maxAge <= 0 {
+		keys.maxAge = 3 * cfg.RefreshInterval
+	}
+	if keys.maxAge <= 0 {
+		keys.maxAge = time.Hour
 	}
 
 	if !cfg.DisableAutoAddMissingAlgorithm {
-		opts = append(opts, jwk.WithPostFetcher(mapMissingAlgorithms(ctx)))
+		keys.next = mapMissingAlgorithms(ctx)
+	}
+
+	opts := []jwk.RegisterOption{
+		jwk.WithRefreshInterval(cfg.RefreshInterval),
+		jwk.WithPostFetcher(&keys),
 	}
 
 	client, err := cfg.HTTPClient.NewClient()
@@ -227,10 +337,11 @@ func (cfg *Provider) toKeySet(ctx context.Context) (jwk.Set, error) {
 
 	opts = append(opts, jwk.WithHTTPClient(client))
 
-	err = cache.Register(cfg.URL, opts...)
+	err = keys.cache.Register(cfg.URL, opts...)
 	if err != nil {
 		return nil, err
 	}
 
-	return jwk.NewCachedSet(cache, cfg.URL), err
+	keys.set = jwk.NewCachedSet(keys.cache, cfg.URL)
+	return &keys, nil
 }
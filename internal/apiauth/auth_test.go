@@ -4,19 +4,32 @@
 package apiauth
 
 import (
+	"crypto/rand"
+	"crypto/rsa"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
 	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/lestrrat-go/jwx/v2/jwe"
 	"github.com/lestrrat-go/jwx/v2/jwk"
 	"github.com/lestrrat-go/jwx/v2/jwt"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/suite"
 	"github.com/xmidt-org/bascule"
+	"github.com/xmidt-org/sallust"
+	"github.com/xmidt-org/skeleton/internal/accesslog"
+	"github.com/xmidt-org/skeleton/internal/log"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
 )
 
 type MockTokenProvider struct {
@@ -160,12 +173,20 @@ func (suite *AuthTestSuite) TestBasicAuth() {
 	suite.Equal(http.StatusOK, response.Code)
 	suite.Equal(1, reached)
 
+	core, logs := observer.New(zap.InfoLevel)
 	badRequest := httptest.NewRequest("GET", "/", nil)
 	badRequest.SetBasicAuth(username, "some-bad-password")
+	badRequest = badRequest.WithContext(sallust.With(badRequest.Context(), zap.New(core)))
 	response = httptest.NewRecorder()
 	h.ServeHTTP(response, badRequest)
 	suite.Equal(http.StatusUnauthorized, response.Code)
 	suite.Equal(1, reached)
+
+	// The rejection is logged with the request-scoped logger.
+	entries := logs.FilterMessage("request rejected by auth").All()
+	suite.Require().Len(entries, 1)
+	suite.Equal(int64(http.StatusUnauthorized), entries[0].ContextMap()["status_code"])
+	suite.NotEmpty(entries[0].ContextMap()[log.ErrorMessage])
 }
 
 func (suite *AuthTestSuite) TestJwtAuth() {
@@ -238,3 +259,348 @@ func (suite *AuthTestSuite) TestJwtAuth() {
 	suite.Equal(http.StatusOK, response.Code)
 	suite.Equal(2, reached)
 }
+
+func (suite *AuthTestSuite) TestSpanAttributes() {
+	set := jwk.NewSet()
+	suite.Require().NoError(set.AddKey(suite.testKeyPub))
+	setBytes, err := json.Marshal(set)
+	suite.Require().NoError(err)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(setBytes)
+	}))
+	defer server.Close()
+
+	auth, err := New(WithConfig(Config{
+		JWT: JWT{
+			KeyProvider: Provider{
+				URL:             server.URL,
+				RefreshInterval: 15 * time.Minute,
+			},
+		},
+	}))
+	suite.Require().NoError(err)
+
+	exporter := tracetest.NewInMemoryExporter()
+	tracer := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)).Tracer("test")
+
+	core, logs := observer.New(zap.InfoLevel)
+	access, err := accesslog.New(accesslog.WithLogger(zap.New(core)))
+	suite.Require().NoError(err)
+	h := access.Middleware("primary")(auth.Then(func(http.ResponseWriter, *http.Request) {}))
+
+	serve := func(token string) map[attribute.Key]attribute.Value {
+		exporter.Reset()
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+
+		ctx, span := tracer.Start(req.Context(), "request")
+		h.ServeHTTP(httptest.NewRecorder(), req.WithContext(ctx))
+		span.End()
+
+		spans := exporter.GetSpans()
+		suite.Require().Len(spans, 1)
+
+		attrs := make(map[attribute.Key]attribute.Value)
+		for _, kv := range spans[0].Attributes {
+			attrs[kv.Key] = kv.Value
+		}
+		return attrs
+	}
+
+	attrs := serve(string(suite.signedJWT))
+	suite.Equal("success", attrs[AuthOutcomeKey].AsString())
+	suite.Equal([]string{"comcast"}, attrs[PartnerIDsKey].AsStringSlice())
+
+	// The principal is added to the access log line.
+	entries := logs.FilterMessage(accesslog.Message).All()
+	suite.Require().Len(entries, 1)
+	suite.Equal(suite.subject, entries[0].ContextMap()[log.UserName])
+
+	attrs = serve("some bad token")
+	suite.Equal("failure", attrs[AuthOutcomeKey].AsString())
+	suite.NotContains(attrs, PartnerIDsKey)
+}
+
+func (suite *AuthTestSuite) TestPluggable() {
+	username := "some-username"
+	config := Config{
+		Basic: Basic{
+			username: "some-password",
+		},
+	}
+
+	var order []string
+	recordingValidator := func(name string) bascule.Validator[*http.Request] {
+		return bascule.AsValidator[*http.Request](
+			func(token bascule.Token) error {
+				order = append(order, name)
+				if token.Principal() == "rejected" {
+					return bascule.ErrUnauthorized
+				}
+				return nil
+			},
+		)
+	}
+
+	headerParser := bascule.AsTokenParser[*http.Request](
+		func(r *http.Request) (bascule.Token, error) {
+			v := r.Header.Get("X-Test-Token")
+			if v == "" {
+				return nil, bascule.ErrMissingCredentials
+			}
+			return bascule.StubToken(v), nil
+		},
+	)
+
+	auth, err := New(
+		WithConfig(config),
+		WithParsers(Parser{
+			Name:   "header",
+			Order:  1,
+			Parser: headerParser,
+		}),
+		WithValidators(
+			Validator{Name: "header", Order: 1, Validator: recordingValidator("header")},
+			Validator{Name: "a", Order: 1, Validator: recordingValidator("a")},
+			Validator{Name: "first", Order: -1, Validator: recordingValidator("first")},
+		),
+	)
+	suite.Require().NoError(err)
+	suite.True(auth.Protected())
+
+	var reached int
+	h := auth.Then(func(w http.ResponseWriter, r *http.Request) {
+		reached++
+	})
+
+	// The contributed parser handles requests without basic credentials.
+	headerRequest := httptest.NewRequest("GET", "/", nil)
+	headerRequest.Header.Set("X-Test-Token", "some-user")
+	response := httptest.NewRecorder()
+	h.ServeHTTP(response, headerRequest)
+	suite.Equal(http.StatusOK, response.Code)
+	suite.Equal(1, reached)
+	suite.Equal([]string{"first", "a", "header"}, order)
+
+	// A contributed validator can reject the token.
+	order = nil
+	rejectedRequest := httptest.NewRequest("GET", "/", nil)
+	rejectedRequest.Header.Set("X-Test-Token", "rejected")
+	response = httptest.NewRecorder()
+	h.ServeHTTP(response, rejectedRequest)
+	suite.Equal(http.StatusForbidden, response.Code)
+	suite.Equal(1, reached)
+	suite.Equal([]string{"first"}, order)
+
+	// The built-in parser and validator still work and run in order.
+	order = nil
+	goodRequest := httptest.NewRequest("GET", "/", nil)
+	goodRequest.SetBasicAuth(username, config.Basic[username])
+	response = httptest.NewRecorder()
+	h.ServeHTTP(response, goodRequest)
+	suite.Equal(http.StatusOK, response.Code)
+	suite.Equal(2, reached)
+	suite.Equal([]string{"first", "a", "header"}, order)
+}
+
+func (suite *AuthTestSuite) TestPluggableOnly() {
+	accept := bascule.AsValidator[*http.Request](func(bascule.Token) error { return nil })
+
+	auth, err := New(
+		WithParsers(Parser{
+			Name:   "stub",
+			Order:  1,
+			Parser: bascule.StubTokenParser[*http.Request]{Token: bascule.StubToken("stub")},
+		}),
+		WithValidators(Validator{Name: "stub", Order: 1, Validator: accept}),
+	)
+	suite.Require().NoError(err)
+	suite.True(auth.Protected())
+
+	_, err = New(
+		WithValidators(Validator{Name: "only", Order: 1, Validator: accept}),
+	)
+	suite.ErrorIs(err, ErrInvalidConfig)
+}
+
+func (suite *AuthTestSuite) TestPluggableInvalid() {
+	stub := bascule.StubTokenParser[*http.Request]{Token: bascule.StubToken("stub")}
+	accept := bascule.AsValidator[*http.Request](func(bascule.Token) error { return nil })
+	config := Config{Basic: Basic{"user": "pass"}}
+
+	tests := []struct {
+		name       string
+		parsers    []Parser
+		validators []Validator
+	}{
+		{
+			name:    "parser without validator",
+			parsers: []Parser{{Name: "stub", Order: 1, Parser: stub}},
+		}, {
+			name:       "validator for another parser",
+			parsers:    []Parser{{Name: "stub", Order: 1, Parser: stub}},
+			validators: []Validator{{Name: "other", Order: 1, Validator: accept}},
+		}, {
+			name:       "parser with the built-in order",
+			parsers:    []Parser{{Name: "stub", Parser: stub}},
+			validators: []Validator{{Name: "stub", Order: 1, Validator: accept}},
+		}, {
+			name:       "validator with the built-in order",
+			parsers:    []Parser{{Name: "stub", Order: -1, Parser: stub}},
+			validators: []Validator{{Name: "stub", Validator: accept}},
+		}, {
+			name:       "nil parser",
+			parsers:    []Parser{{Name: "stub", Order: 1}},
+			validators: []Validator{{Name: "stub", Order: 1, Validator: accept}},
+		}, {
+			name:       "nil validator",
+			parsers:    []Parser{{Name: "stub", Order: 1, Parser: stub}},
+			validators: []Validator{{Name: "stub", Order: 1}},
+		},
+	}
+
+	for _, tc := range tests {
+		suite.Run(tc.name, func() {
+			_, err := New(
+				WithConfig(config),
+				WithParsers(tc.parsers...),
+				WithValidators(tc.validators...),
+			)
+			suite.ErrorIs(err, ErrInvalidConfig)
+		})
+	}
+}
+
+func (suite *AuthTestSuite) newEncryptionKey(kid string) (jwk.Key, jwk.Key) {
+	raw, err := rsa.GenerateKey(rand.Reader, 2048)
+	suite.Require().NoError(err)
+
+	priv, err := jwk.FromRaw(raw)
+	suite.Require().NoError(err)
+	suite.Require().NoError(priv.Set(jwk.KeyIDKey, kid))
+
+	pub, err := priv.PublicKey()
+	suite.Require().NoError(err)
+
+	return priv, pub
+}
+
+func (suite *AuthTestSuite) encrypt(payload []byte, key jwk.Key) string {
+	return suite.encryptWith(jwa.RSA_OAEP_256, payload, key)
+}
+
+func (suite *AuthTestSuite) encryptWith(alg jwa.KeyEncryptionAlgorithm, payload []byte, key jwk.Key) string {
+	buf, err := jwe.Encrypt(payload,
+		jwe.WithKey(alg, key),
+		jwe.WithContentEncryption(jwa.A256GCM),
+	)
+	suite.Require().NoError(err)
+	return string(buf)
+}
+
+func (suite *AuthTestSuite) TestJweAuth() {
+	set := jwk.NewSet()
+	suite.Require().NoError(set.AddKey(suite.testKeyPub))
+	setBytes, err := json.Marshal(set)
+	suite.Require().NoError(err)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(setBytes)
+	}))
+	defer server.Close()
+
+	oldPriv, oldPub := suite.newEncryptionKey("old")
+	newPriv, newPub := suite.newEncryptionKey("new")
+	_, otherPub := suite.newEncryptionKey("other")
+
+	// The old key is in a file as a JWK, the new key is inline as PEM.
+	oldJSON, err := json.Marshal(oldPriv)
+	suite.Require().NoError(err)
+	file := filepath.Join(suite.T().TempDir(), "old.json")
+	suite.Require().NoError(os.WriteFile(file, oldJSON, 0600))
+
+	newPEM, err := jwk.EncodePEM(newPriv)
+	suite.Require().NoError(err)
+
+	for _, required := range []bool{false, true} {
+		config := Config{
+			JWT: JWT{
+				KeyProvider: Provider{
+					URL:             server.URL,
+					RefreshInterval: 15 * time.Minute,
+				},
+				Decryption: Decryption{
+					Keys: []DecryptionKey{
+						{File: file},
+						{Key: string(newPEM)},
+					},
+					Required: required,
+				},
+			},
+		}
+
+		auth, err := New(WithConfig(config))
+		suite.Require().NoError(err)
+
+		var reached int
+		h := auth.Then(func(w http.ResponseWriter, r *http.Request) {
+			t, _ := bascule.GetFrom(r)
+			suite.Equal(suite.subject, t.Principal())
+			reached++
+		})
+
+		send := func(token string) int {
+			req := httptest.NewRequest("GET", "/", nil)
+			req.Header.Set("Authorization", "Bearer "+token)
+			resp := httptest.NewRecorder()
+			h.ServeHTTP(resp, req)
+			return resp.Code
+		}
+
+		suite.Equal(http.StatusOK, send(suite.encrypt(suite.signedJWT, oldPub)))
+		suite.Equal(http.StatusOK, send(suite.encrypt(suite.signedJWT, newPub)))
+		suite.Equal(http.StatusBadRequest, send(suite.encrypt(suite.signedJWT, otherPub)))
+		suite.Equal(http.StatusBadRequest, send(suite.encrypt([]byte("not a jwt"), newPub)))
+
+		// The PEM key has no algorithm, but RSA1_5 is still refused.
+		suite.Equal(http.StatusBadRequest, send(suite.encryptWith(jwa.RSA1_5, suite.signedJWT, newPub)))
+
+		if required {
+			suite.Equal(http.StatusBadRequest, send(string(suite.signedJWT)))
+			suite.Equal(2, reached)
+		} else {
+			suite.Equal(http.StatusOK, send(string(suite.signedJWT)))
+			suite.Equal(3, reached)
+		}
+	}
+
+	_, err = New(WithConfig(Config{
+		JWT: JWT{
+			KeyProvider: Provider{URL: server.URL},
+			Decryption:  Decryption{Required: true},
+		},
+	}))
+	suite.ErrorIs(err, ErrInvalidConfig)
+
+	_, err = New(WithConfig(Config{
+		JWT: JWT{
+			KeyProvider: Provider{
+				URL:             server.URL,
+				RefreshInterval: 15 * time.Minute,
+			},
+			Decryption: Decryption{
+				Keys: []DecryptionKey{{Key: string(mustJSON(suite, oldPub))}},
+			},
+		},
+	}))
+	suite.ErrorIs(err, ErrInvalidConfig)
+}
+
+func mustJSON(suite *AuthTestSuite, v any) []byte {
+	buf, err := json.Marshal(v)
+	suite.Require().NoError(err)
+	return buf
+}
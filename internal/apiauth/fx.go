@@ -4,17 +4,31 @@
 package apiauth
 
 import (
+	kit "github.com/go-kit/kit/metrics"
+	"github.com/xmidt-org/skeleton/internal/health"
 	"go.uber.org/fx"
+	"go.uber.org/zap"
 )
 
 type AuthIn struct {
 	fx.In
-	Config Config
+	Config         Config
+	Logger         *zap.Logger
+	ReloadFailures kit.Counter `name:"auth_key_reload_failure_count"`
+
+	// Parsers are additional token parsers provided by other modules.
+	Parsers []Parser `group:"auth.parsers"`
+
+	// Validators are additional token validators provided by other modules.
+	Validators []Validator `group:"auth.validators"`
 }
 
 type AuthOut struct {
 	fx.Out
 	Auth *Auth
+
+	// Checks are the health checks for the auth dependencies.
+	Checks []health.Check `group:"health.checks,flatten"`
 }
 
 var Module = fx.Module("auth",
@@ -22,10 +36,16 @@ var Module = fx.Module("auth",
 		func(in AuthIn) (AuthOut, error) {
 			auth, err := New(
 				WithConfig(in.Config),
+				WithLogger(in.Logger),
+				WithReloadFailures(in.ReloadFailures),
+				WithParsers(in.Parsers...),
+				WithValidators(in.Validators...),
 			)
 
 			return AuthOut{
-				Auth: auth}, err
+				Auth:   auth,
+				Checks: auth.HealthChecks(),
+			}, err
 		},
 	),
 )
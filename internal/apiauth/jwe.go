@@ -0,0 +1,295 @@
+// SPDX-FileCopyrightText: 2024 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package apiauth
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	kit "github.com/go-kit/kit/metrics"
+	"github.com/go-kit/kit/metrics/discard"
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/lestrrat-go/jwx/v2/jwe"
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/xmidt-org/bascule"
+	"go.uber.org/zap"
+)
+
+// Decryption holds the configuration for accepting encrypted (JWE) tokens.
+// The decrypted payload must be a signed JWT, which is then verified using the
+// keys from the JWT KeyProvider.
+type Decryption struct {
+	// Keys are the private keys used to decrypt tokens.  All the keys are
+	// tried, so a new key can be added before the old key is removed to
+	// support rotation.
+	Keys []DecryptionKey
+
+	// Required is a flag that if set to true, will reject tokens that are not
+	// encrypted.
+	Required bool
+
+	// ReloadInterval is how often the keys are reloaded.  This allows key
+	// files to be rotated without restarting.  The keys are reloaded in the
+	// background, and if a reload fails the previous keys are kept.  If this
+	// is not set, the keys are only loaded at startup.
+	ReloadInterval time.Duration
+
+	// Algorithms are the key management algorithms accepted.  Defaults to
+	// the RSA-OAEP, ECDH-ES and AES key wrap algorithms.  RSA1_5 is never
+	// accepted since it is open to padding oracle attacks.
+	Algorithms []string
+}
+
+// defaultDecryptionAlgorithms are the key management algorithms accepted
+// when none are configured.
+var defaultDecryptionAlgorithms = []jwa.KeyEncryptionAlgorithm{
+	jwa.RSA_OAEP,
+	jwa.RSA_OAEP_256,
+	jwa.RSA_OAEP_384,
+	jwa.RSA_OAEP_512,
+	jwa.ECDH_ES,
+	jwa.ECDH_ES_A128KW,
+	jwa.ECDH_ES_A192KW,
+	jwa.ECDH_ES_A256KW,
+	jwa.A128KW,
+	jwa.A192KW,
+	jwa.A256KW,
+	jwa.A128GCMKW,
+	jwa.A192GCMKW,
+	jwa.A256GCMKW,
+}
+
+// DecryptionKey is a private key used to decrypt tokens, or a symmetric (oct)
+// key for the AES key wrap algorithms.  Exactly one of File or Key must be
+// set.
+type DecryptionKey struct {
+	// File is the path to a file containing a JWK, a JWK set or PEM encoded keys.
+	File string
+
+	// Key is an inline JWK, JWK set or PEM encoded keys.
+	Key string
+}
+
+func (d *Decryption) enabled() bool {
+	return len(d.Keys) > 0
+}
+
+func (d *Decryption) algorithms() ([]jwa.KeyEncryptionAlgorithm, error) {
+	if len(d.Algorithms) == 0 {
+		return defaultDecryptionAlgorithms, nil
+	}
+
+	algs := make([]jwa.KeyEncryptionAlgorithm, 0, len(d.Algorithms))
+	for _, name := range d.Algorithms {
+		var alg jwa.KeyEncryptionAlgorithm
+		if err := alg.Accept(name); err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidConfig, err)
+		}
+		if alg == jwa.RSA1_5 {
+			return nil, fmt.Errorf("%w: the %s algorithm is not allowed", ErrInvalidConfig, alg)
+		}
+		algs = append(algs, alg)
+	}
+
+	return algs, nil
+}
+
+func (k *DecryptionKey) load() ([]jwk.Key, error) {
+	var buf []byte
+
+	switch {
+	case k.File != "" && k.Key != "":
+		return nil, fmt.Errorf("%w: only one of file or key may be set", ErrInvalidConfig)
+	case k.File != "":
+		var err error
+		buf, err = os.ReadFile(k.File)
+		if err != nil {
+			return nil, err
+		}
+	case k.Key != "":
+		buf = []byte(k.Key)
+	default:
+		return nil, fmt.Errorf("%w: a decryption key requires a file or key", ErrInvalidConfig)
+	}
+
+	buf = bytes.TrimSpace(buf)
+	set, err := jwk.Parse(buf, jwk.WithPEM(bytes.HasPrefix(buf, []byte("-----"))))
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]jwk.Key, 0, set.Len())
+	for i := range set.Len() {
+		key, _ := set.Key(i)
+
+		// Symmetric keys are used as they are by the AES key wrap algorithms.
+		if key.KeyType() != jwa.OctetSeq {
+			if private, err := jwk.IsPrivateKey(key); err != nil || !private {
+				return nil, fmt.Errorf("%w: decryption keys must be private or symmetric keys", ErrInvalidConfig)
+			}
+		}
+		keys = append(keys, key)
+	}
+
+	return keys, nil
+}
+
+// decrypter is a jwe.KeyProvider that provides the configured decryption keys.
+type decrypter struct {
+	config     Decryption
+	algorithms []jwa.KeyEncryptionAlgorithm
+	logger     *zap.Logger
+	failures   kit.Counter
+	now        func() time.Time
+
+	keys atomic.Pointer[[]jwk.Key]
+
+	m         sync.Mutex
+	loaded    time.Time
+	reloading bool
+}
+
+func newDecrypter(cfg Decryption, logger *zap.Logger, failures kit.Counter) (*decrypter, error) {
+	algs, err := cfg.algorithms()
+	if err != nil {
+		return nil, err
+	}
+
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	if failures == nil {
+		failures = discard.NewCounter()
+	}
+
+	d := decrypter{
+		config:     cfg,
+		algorithms: algs,
+		logger:     logger,
+		failures:   failures,
+		now:        time.Now,
+	}
+
+	keys, err := d.load()
+	if err != nil {
+		return nil, err
+	}
+
+	d.keys.Store(&keys)
+	d.loaded = d.now()
+
+	return &d, nil
+}
+
+func (d *decrypter) load() ([]jwk.Key, error) {
+	var keys []jwk.Key
+	for _, k := range d.config.Keys {
+		more, err := k.load()
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, more...)
+	}
+
+	return keys, nil
+}
+
+// current returns the keys.  When the reload interval has passed they are
+// reloaded in the background, so requests never wait on the files.
+func (d *decrypter) current() []jwk.Key {
+	if d.config.ReloadInterval > 0 {
+		d.m.Lock()
+		if !d.reloading && d.now().Sub(d.loaded) >= d.config.ReloadInterval {
+			d.reloading = true
+			go d.reload()
+		}
+		d.m.Unlock()
+	}
+
+	return *d.keys.Load()
+}
+
+// reload loads the keys again.  If that fails, the failure is logged and
+// counted and the previous keys are used until the next reload.
+func (d *decrypter) reload() {
+	keys, err := d.load()
+	if err != nil {
+		d.logger.Error("unable to reload the decryption keys", zap.Error(err))
+		d.failures.Add(1)
+	} else {
+		d.keys.Store(&keys)
+	}
+
+	d.m.Lock()
+	d.loaded = d.now()
+	d.reloading = false
+	d.m.Unlock()
+}
+
+// FetchKeys provides the keys that match the recipient's key id and algorithm.
+func (d *decrypter) FetchKeys(_ context.Context, sink jwe.KeySink, r jwe.Recipient, _ *jwe.Message) error {
+	alg := r.Headers().Algorithm()
+	kid := r.Headers().KeyID()
+
+	// Keys without an algorithm, such as PEM keys, would be used with any.
+	if !slices.Contains(d.algorithms, alg) {
+		return fmt.Errorf("key management algorithm %s is not allowed", alg)
+	}
+
+	for _, key := range d.current() {
+		if kid != "" && key.KeyID() != "" && kid != key.KeyID() {
+			continue
+		}
+
+		if a := key.Algorithm().String(); a != "" && a != alg.String() {
+			continue
+		}
+
+		// Symmetric keys only go with the symmetric algorithms.
+		if (key.KeyType() == jwa.OctetSeq) != alg.IsSymmetric() {
+			continue
+		}
+
+		if usage := key.KeyUsage(); usage != "" && usage != jwk.ForEncryption.String() {
+			continue
+		}
+
+		sink.Key(alg, key)
+	}
+
+	return nil
+}
+
+// jweTokenParser decrypts JWE tokens before passing them to the JWT parser.
+type jweTokenParser struct {
+	decrypter *decrypter
+	required  bool
+	next      bascule.TokenParser[string]
+}
+
+func (p *jweTokenParser) Parse(ctx context.Context, value string) (bascule.Token, error) {
+	// JWE compact serialization has 5 parts, JWS has 3.
+	if strings.Count(value, ".") != 4 {
+		if p.required {
+			return nil, errors.Join(bascule.ErrInvalidCredentials,
+				errors.New("encrypted token required"))
+		}
+		return p.next.Parse(ctx, value)
+	}
+
+	payload, err := jwe.Decrypt([]byte(value), jwe.WithKeyProvider(p.decrypter))
+	if err != nil {
+		return nil, errors.Join(bascule.ErrInvalidCredentials, err)
+	}
+
+	return p.next.Parse(ctx, string(payload))
+}
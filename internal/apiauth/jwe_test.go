@@ -0,0 +1,157 @@
+// SPDX-FileCopyrightText: 2024 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package apiauth
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/metrics/generic"
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/lestrrat-go/jwx/v2/jwe"
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestDecrypterReload(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	write := func(file string, key jwk.Key) {
+		buf, err := json.Marshal(key)
+		require.NoError(err)
+		require.NoError(os.WriteFile(file, buf, 0600))
+	}
+
+	file := filepath.Join(t.TempDir(), "key.json")
+	write(file, mustGenerateKey("rsa.private.first"))
+
+	core, logs := observer.New(zap.ErrorLevel)
+	failures := generic.NewCounter("failures")
+	d, err := newDecrypter(Decryption{
+		Keys:           []DecryptionKey{{File: file}},
+		ReloadInterval: time.Minute,
+	}, zap.New(core), failures)
+	require.NoError(err)
+
+	var now atomic.Int64
+	now.Store(time.Now().UnixNano())
+	d.now = func() time.Time { return time.Unix(0, now.Load()) }
+	advance := func(by time.Duration) {
+		now.Add(int64(by))
+	}
+
+	// reloaded waits for the reload in the background.
+	reloaded := func() {
+		require.Eventually(func() bool {
+			d.m.Lock()
+			defer d.m.Unlock()
+			return !d.reloading
+		}, time.Second, time.Millisecond)
+	}
+
+	keys := d.current()
+	require.Len(keys, 1)
+	assert.Equal("first", keys[0].KeyID())
+
+	// Not reloaded until the interval has passed.
+	write(file, mustGenerateKey("rsa.private.second"))
+	advance(30 * time.Second)
+	assert.Equal("first", d.current()[0].KeyID())
+	reloaded()
+	assert.Equal("first", d.current()[0].KeyID())
+
+	// The reload happens in the background.
+	advance(time.Minute)
+	d.current()
+	reloaded()
+	assert.Equal("second", d.current()[0].KeyID())
+
+	// A broken file keeps the previous keys, and the failure is logged and
+	// counted.
+	require.NoError(os.WriteFile(file, []byte("garbage"), 0600))
+	advance(time.Minute)
+	d.current()
+	reloaded()
+	assert.Equal("second", d.current()[0].KeyID())
+	assert.Equal(1.0, failures.Value())
+	assert.Equal(1, logs.FilterMessage("unable to reload the decryption keys").Len())
+}
+
+func TestDecrypterAlgorithms(t *testing.T) {
+	key := mustGenerateKey("rsa.private.first")
+	buf, err := json.Marshal(key)
+	require.NoError(t, err)
+	keys := []DecryptionKey{{Key: string(buf)}}
+
+	d, err := newDecrypter(Decryption{Keys: keys}, nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, defaultDecryptionAlgorithms, d.algorithms)
+	assert.NotContains(t, d.algorithms, jwa.RSA1_5)
+
+	d, err = newDecrypter(Decryption{Keys: keys, Algorithms: []string{"RSA-OAEP-256"}}, nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []jwa.KeyEncryptionAlgorithm{jwa.RSA_OAEP_256}, d.algorithms)
+
+	for _, algs := range [][]string{{"RSA1_5"}, {"RSA-OAEP", "nonsense"}} {
+		_, err = newDecrypter(Decryption{Keys: keys, Algorithms: algs}, nil, nil)
+		assert.ErrorIs(t, err, ErrInvalidConfig)
+	}
+}
+
+func TestDecrypterSymmetric(t *testing.T) {
+	secret := make([]byte, 32)
+	_, err := rand.Read(secret)
+	require.NoError(t, err)
+
+	key, err := jwk.FromRaw(secret)
+	require.NoError(t, err)
+	buf, err := json.Marshal(key)
+	require.NoError(t, err)
+
+	// The RSA key is skipped for the symmetric algorithms.
+	private, err := json.Marshal(mustGenerateKey("rsa.private.first"))
+	require.NoError(t, err)
+
+	d, err := newDecrypter(Decryption{
+		Keys: []DecryptionKey{{Key: string(private)}, {Key: string(buf)}},
+	}, nil, nil)
+	require.NoError(t, err)
+
+	for _, alg := range []jwa.KeyEncryptionAlgorithm{jwa.A256KW, jwa.A256GCMKW} {
+		t.Run(alg.String(), func(t *testing.T) {
+			token, err := jwe.Encrypt([]byte("payload"),
+				jwe.WithKey(alg, key),
+				jwe.WithContentEncryption(jwa.A256GCM),
+			)
+			require.NoError(t, err)
+
+			payload, err := jwe.Decrypt(token, jwe.WithKeyProvider(d))
+			require.NoError(t, err)
+			assert.Equal(t, "payload", string(payload))
+		})
+	}
+}
+
+func TestDecryptionKeyInvalid(t *testing.T) {
+	tests := []DecryptionKey{
+		{},
+		{File: "a", Key: "b"},
+		{File: filepath.Join(t.TempDir(), "missing.json")},
+		{Key: "not a key"},
+	}
+
+	for _, tc := range tests {
+		_, err := newDecrypter(Decryption{Keys: []DecryptionKey{tc}}, nil, nil)
+		assert.Error(t, err)
+	}
+}
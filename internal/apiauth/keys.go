@@ -0,0 +1,88 @@
+// SPDX-FileCopyrightText: 2024 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package apiauth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/xmidt-org/skeleton/internal/health"
+)
+
+var (
+	errKeysNotFetched = errors.New("the public keys have not been fetched")
+	errStaleKeys      = errors.New("the public keys are stale")
+)
+
+// keySet is the cached set of public keys from the key provider.  It records
+// when the keys were last fetched successfully so their freshness can be
+// checked.
+type keySet struct {
+	set    jwk.Set
+	cache  *jwk.Cache
+	url    string
+	maxAge time.Duration
+	next   jwk.PostFetcher
+
+	// fetched is the unix nano time of the last successful fetch.
+	fetched atomic.Int64
+}
+
+// PostFetch records the fetch after the next post fetcher accepts the keys.
+func (k *keySet) PostFetch(url string, set jwk.Set) (jwk.Set, error) {
+	if k.next != nil {
+		var err error
+		set, err = k.next.PostFetch(url, set)
+		if err != nil {
+			return set, err
+		}
+	}
+
+	k.fetched.Store(time.Now().UnixNano())
+	return set, nil
+}
+
+// check fails when the keys are older than the max age and can't be fetched
+// again.  The cache schedules its own refreshes, from the refresh interval or
+// the caching headers of the key provider, which can be further apart than
+// the max age, so old keys are refreshed before the check fails.  Keys that
+// have never been fetched are fetched first.
+func (k *keySet) check(ctx context.Context) error {
+	fetched := k.fetched.Load()
+	if fetched == 0 {
+		if _, err := k.cache.Refresh(ctx, k.url); err != nil {
+			return errors.Join(errKeysNotFetched, err)
+		}
+		return nil
+	}
+
+	if age := time.Since(time.Unix(0, fetched)); age > k.maxAge {
+		if _, err := k.cache.Refresh(ctx, k.url); err != nil {
+			return fmt.Errorf("%w: last fetched %s ago: %w", errStaleKeys, age.Round(time.Second), err)
+		}
+	}
+
+	return nil
+}
+
+// HealthChecks returns the checks for the auth dependencies: a critical
+// readiness check that the public keys are fresh when JWT auth is used.
+func (auth *Auth) HealthChecks() []health.Check {
+	if auth == nil || auth.keys == nil {
+		return nil
+	}
+
+	return []health.Check{
+		{
+			Name:     "auth.keys",
+			Check:    auth.keys.check,
+			Probes:   health.Readiness,
+			Critical: true,
+		},
+	}
+}
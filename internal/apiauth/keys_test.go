@@ -0,0 +1,93 @@
+// SPDX-FileCopyrightText: 2024 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package apiauth
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeysHealthCheck(t *testing.T) {
+	var down atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		if down.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"keys":[{"kty":"oct","k":"c2VjcmV0","alg":"HS256","kid":"1"}]}`))
+	}))
+	defer server.Close()
+
+	auth, err := New(WithConfig(Config{
+		JWT: JWT{
+			KeyProvider: Provider{
+				URL:             server.URL,
+				RefreshInterval: time.Hour,
+			},
+		},
+	}))
+	require.NoError(t, err)
+
+	checks := auth.HealthChecks()
+	require.Len(t, checks, 1)
+	check := checks[0].Check
+	ctx := context.Background()
+
+	// The keys are fetched by the first check when needed.
+	down.Store(true)
+	assert.ErrorIs(t, check(ctx), errKeysNotFetched)
+
+	down.Store(false)
+	assert.NoError(t, check(ctx))
+	assert.NoError(t, check(ctx))
+
+	// Old keys are fetched again before the check fails.
+	old := time.Now().Add(-4 * time.Hour).UnixNano()
+	auth.keys.fetched.Store(old)
+	assert.NoError(t, check(ctx))
+	assert.Greater(t, auth.keys.fetched.Load(), old)
+
+	down.Store(true)
+	auth.keys.fetched.Store(old)
+	assert.ErrorIs(t, check(ctx), errStaleKeys)
+}
+
+func TestKeysHealthCheckCacheHeaders(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		// Without a refresh interval the cache waits as long as the key
+		// provider says, which is longer than the default max age.
+		w.Header().Set("Cache-Control", "max-age=7200")
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"keys":[{"kty":"oct","k":"c2VjcmV0","alg":"HS256","kid":"1"}]}`))
+	}))
+	defer server.Close()
+
+	auth, err := New(WithConfig(Config{
+		JWT: JWT{
+			KeyProvider: Provider{URL: server.URL},
+		},
+	}))
+	require.NoError(t, err)
+
+	check := auth.HealthChecks()[0].Check
+	ctx := context.Background()
+	require.NoError(t, check(ctx))
+
+	auth.keys.fetched.Store(time.Now().Add(-90 * time.Minute).UnixNano())
+	assert.NoError(t, check(ctx))
+}
+
+func TestNoKeysHealthCheck(t *testing.T) {
+	auth, err := New(WithConfig(Config{Disable: true}))
+	require.NoError(t, err)
+	assert.Empty(t, auth.HealthChecks())
+}
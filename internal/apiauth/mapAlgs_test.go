@@ -210,7 +210,7 @@ func TestGetKeys(t *testing.T) {
 	require.NoError(t, err)
 	require.NotNil(t, keySet)
 
-	keys := keySet.Keys(ctx)
+	keys := keySet.set.Keys(ctx)
 	require.True(t, keys.Next(ctx))
 
 	key := keys.Pair().Value.(jwk.Key)
@@ -6,6 +6,9 @@ package apiauth
 import (
 	"fmt"
 	"reflect"
+
+	kit "github.com/go-kit/kit/metrics"
+	"go.uber.org/zap"
 )
 
 // Option is an interface that is used to apply options to the Auth struct.
@@ -27,6 +30,41 @@ func WithConfig(c Config) optionFunc {
 	}
 }
 
+// WithParsers adds token parsers to the authenticator.  Multiple calls are
+// cumulative.  See Parser for how the parsers are ordered.
+func WithParsers(p ...Parser) optionFunc {
+	return func(a *Auth) error {
+		a.parsers = append(a.parsers, p...)
+		return nil
+	}
+}
+
+// WithValidators adds token validators to the authenticator.  Multiple calls
+// are cumulative.  See Validator for how the validators are ordered.
+func WithValidators(v ...Validator) optionFunc {
+	return func(a *Auth) error {
+		a.validators = append(a.validators, v...)
+		return nil
+	}
+}
+
+// WithLogger sets the logger for the failures outside of requests, such as
+// reloading the decryption keys.
+func WithLogger(logger *zap.Logger) optionFunc {
+	return func(a *Auth) error {
+		a.logger = logger
+		return nil
+	}
+}
+
+// WithReloadFailures sets the counter of the failed decryption key reloads.
+func WithReloadFailures(c kit.Counter) optionFunc {
+	return func(a *Auth) error {
+		a.reloadFailures = c
+		return nil
+	}
+}
+
 //------------------------------------------------------------------------------
 
 func validate() optionFunc {
@@ -41,7 +79,15 @@ func validate() optionFunc {
 			}
 		}
 
-		if reflect.DeepEqual(a.config, Config{}) {
+		if a.config.JWT.Decryption.Required && !a.config.JWT.Decryption.enabled() {
+			return fmt.Errorf("%w: encrypted tokens cannot be required without decryption keys", ErrInvalidConfig)
+		}
+
+		if err := validatePlugins(a.parsers, a.validators); err != nil {
+			return err
+		}
+
+		if reflect.DeepEqual(a.config, Config{}) && len(a.parsers) == 0 {
 			return fmt.Errorf("%w: empty configuration is not valid, set 'disable' to true if no validation is wanted", ErrInvalidConfig)
 		}
 
@@ -0,0 +1,63 @@
+// SPDX-FileCopyrightText: 2024 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package apiauth
+
+import (
+	"context"
+
+	"github.com/xmidt-org/bascule"
+)
+
+// The claims that hold the partner ids a token is allowed to access.
+const (
+	AllowedResourcesKey = "allowedResources"
+	AllowedPartnersKey  = "allowedPartners"
+)
+
+// PartnerIDs returns the partner ids of the authenticated token in the
+// context.  Nil is returned if the request was not authenticated or the token
+// does not have any partner ids.
+func PartnerIDs(ctx context.Context) []string {
+	token, ok := bascule.Get(ctx)
+	if !ok {
+		return nil
+	}
+
+	var attrs bascule.AttributesAccessor
+	if !bascule.TokenAs(token, &attrs) {
+		return nil
+	}
+
+	raw, ok := bascule.GetAttribute[any](attrs, AllowedResourcesKey, AllowedPartnersKey)
+	if !ok {
+		return nil
+	}
+
+	switch v := raw.(type) {
+	case []string:
+		return v
+	case []any:
+		partners := make([]string, 0, len(v))
+		for _, p := range v {
+			if s, ok := p.(string); ok {
+				partners = append(partners, s)
+			}
+		}
+		return partners
+	case string:
+		return []string{v}
+	}
+
+	return nil
+}
+
+// PartnerID returns the first partner id of the authenticated token in the
+// context or an empty string if there is none.
+func PartnerID(ctx context.Context) string {
+	if partners := PartnerIDs(ctx); len(partners) > 0 {
+		return partners[0]
+	}
+
+	return ""
+}
@@ -0,0 +1,155 @@
+// SPDX-FileCopyrightText: 2024 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package apiauth
+
+import (
+	"cmp"
+	"fmt"
+	"net/http"
+	"slices"
+
+	"github.com/xmidt-org/bascule"
+)
+
+// Parser is a token parser that is added to the authenticator in addition to
+// the built-in Basic or JWT parser.  Parsers are contributed through the
+// "auth.parsers" fx value group.
+//
+// Parsers are run in ascending Order, with ties broken by Name.  The built-in
+// parser has an Order of 0, so contributed parsers with a negative Order run
+// before it and those with a positive Order after it.  Zero is reserved for
+// the built-in parser.  The first parser that does not return
+// bascule.ErrMissingCredentials determines the token.
+//
+// The built-in validators accept the token types they don't know, so every
+// contributed parser needs a contributed Validator with the same Name to
+// check its tokens.
+type Parser struct {
+	// Name identifies the parser and the validator of its tokens.  It is
+	// also used to provide a stable ordering.
+	Name string
+
+	// Order is the relative position of the parser.  It must not be zero.
+	Order int
+
+	// Parser is the token parser to use.
+	Parser bascule.TokenParser[*http.Request]
+}
+
+// Validator is a token validator that is added to the authenticator in
+// addition to the built-in Basic or JWT validator.  Validators are contributed
+// through the "auth.validators" fx value group.
+//
+// Validators are run in ascending Order, with ties broken by Name.  The
+// built-in validator has an Order of 0, so contributed validators with a
+// negative Order run before it and those with a positive Order after it.
+// Zero is reserved for the built-in validator.  All validators must accept
+// the token.
+type Validator struct {
+	// Name identifies the validator.  It is used to provide a stable ordering.
+	Name string
+
+	// Order is the relative position of the validator.  It must not be zero.
+	Order int
+
+	// Validator is the token validator to use.
+	Validator bascule.Validator[*http.Request]
+}
+
+// ordered is implemented by the types that are sorted before being added to
+// the authenticator.
+type ordered interface {
+	Parser | Validator
+}
+
+// sortOrdered sorts the list by Order then Name.  The builtin entry (if any)
+// has an Order of 0, which the contributed entries can't use.
+func sortOrdered[T ordered](builtin *T, list []T) []T {
+	type entry struct {
+		name  string
+		order int
+		value T
+	}
+
+	entries := make([]entry, 0, len(list)+1)
+	if builtin != nil {
+		entries = append(entries, entry{value: *builtin})
+	}
+	for _, v := range list {
+		e := entry{value: v}
+		switch t := any(v).(type) {
+		case Parser:
+			e.name, e.order = t.Name, t.Order
+		case Validator:
+			e.name, e.order = t.Name, t.Order
+		}
+		entries = append(entries, e)
+	}
+
+	slices.SortStableFunc(entries, func(a, b entry) int {
+		if c := cmp.Compare(a.order, b.order); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.name, b.name)
+	})
+
+	rv := make([]T, 0, len(entries))
+	for _, e := range entries {
+		rv = append(rv, e.value)
+	}
+	return rv
+}
+
+// validatePlugins checks that the contributed parsers and validators are set,
+// don't use the Order of the built-in ones, and that every contributed parser
+// has a validator for its tokens.
+func validatePlugins(parsers []Parser, validators []Validator) error {
+	for _, v := range validators {
+		if v.Validator == nil {
+			return fmt.Errorf("%w: validator '%s' is nil", ErrInvalidConfig, v.Name)
+		}
+		if v.Order == 0 {
+			return fmt.Errorf("%w: validator '%s' must not have an order of 0", ErrInvalidConfig, v.Name)
+		}
+	}
+
+	for _, p := range parsers {
+		if p.Parser == nil {
+			return fmt.Errorf("%w: parser '%s' is nil", ErrInvalidConfig, p.Name)
+		}
+		if p.Order == 0 {
+			return fmt.Errorf("%w: parser '%s' must not have an order of 0", ErrInvalidConfig, p.Name)
+		}
+
+		// The validators were checked above, so the match is never nil.
+		validated := slices.ContainsFunc(validators, func(v Validator) bool {
+			return v.Name == p.Name
+		})
+		if !validated {
+			return fmt.Errorf("%w: parser '%s' has no validator with the same name", ErrInvalidConfig, p.Name)
+		}
+	}
+
+	return nil
+}
+
+func toTokenParsers(list []Parser) []bascule.TokenParser[*http.Request] {
+	rv := make([]bascule.TokenParser[*http.Request], 0, len(list))
+	for _, p := range list {
+		if p.Parser != nil {
+			rv = append(rv, p.Parser)
+		}
+	}
+	return rv
+}
+
+func toValidators(list []Validator) []bascule.Validator[*http.Request] {
+	rv := make([]bascule.Validator[*http.Request], 0, len(list))
+	for _, v := range list {
+		if v.Validator != nil {
+			rv = append(rv, v.Validator)
+		}
+	}
+	return rv
+}
@@ -0,0 +1,182 @@
+// SPDX-FileCopyrightText: 2024 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+// Package cors implements the cross-origin resource sharing policies that
+// let browsers call the servers from other origins.
+package cors
+
+import (
+	"net/http"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Config is a CORS policy.  A policy without origins allows none.
+type Config struct {
+	// Origins are the allowed origins.  An origin is either exact, such as
+	// https://tools.example.com, or has * wildcards, such as
+	// https://*.example.com.  A lone * allows every origin.
+	Origins []string
+
+	// OriginPatterns are regular expressions the whole origin, in lower
+	// case, has to match to be allowed.
+	OriginPatterns []string
+
+	// Methods are the methods allowed in preflight requests.  Defaults to
+	// GET, HEAD and POST.
+	Methods []string
+
+	// Headers are the request headers allowed in preflight requests.  A lone
+	// * allows the headers the preflight asks for.
+	Headers []string
+
+	// ExposeHeaders are the response headers the browser lets the caller
+	// read.
+	ExposeHeaders []string
+
+	// Credentials allows cookies and the Authorization header to be sent.  It
+	// can't be used with the lone * origin.
+	Credentials bool
+
+	// MaxAge is how long the browser may cache the preflight response.
+	MaxAge time.Duration
+}
+
+// Enabled reports whether any origins are allowed.
+func (c Config) Enabled() bool {
+	return len(c.Origins) > 0 || len(c.OriginPatterns) > 0
+}
+
+// Policy answers the preflight requests and adds the CORS headers to the
+// responses of the allowed origins.
+type Policy struct {
+	config   Config
+	all      bool
+	exact    []string
+	patterns []*regexp.Regexp
+	methods  string
+	headers  string
+	expose   string
+	maxAge   string
+}
+
+// New creates the policy.
+func New(opts ...Option) (*Policy, error) {
+	var p Policy
+
+	for _, opt := range opts {
+		if opt != nil {
+			if err := opt.apply(&p); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	methods := p.config.Methods
+	if len(methods) == 0 {
+		methods = []string{http.MethodGet, http.MethodHead, http.MethodPost}
+	}
+	p.methods = strings.Join(methods, ", ")
+	p.headers = strings.Join(p.config.Headers, ", ")
+	p.expose = strings.Join(p.config.ExposeHeaders, ", ")
+	if p.config.MaxAge > 0 {
+		p.maxAge = strconv.Itoa(int(p.config.MaxAge.Seconds()))
+	}
+
+	return &p, nil
+}
+
+// IsPreflight reports whether the request is a CORS preflight request.
+func IsPreflight(req *http.Request) bool {
+	return req.Method == http.MethodOptions &&
+		req.Header.Get("Origin") != "" &&
+		req.Header.Get("Access-Control-Request-Method") != ""
+}
+
+// Preflight answers the preflight request with a 204.  The CORS headers are
+// only included if the origin is allowed, which makes the browser refuse the
+// request otherwise.
+func (p *Policy) Preflight(resp http.ResponseWriter, req *http.Request) {
+	h := resp.Header()
+	h.Add("Vary", "Origin")
+	h.Add("Vary", "Access-Control-Request-Method")
+	h.Add("Vary", "Access-Control-Request-Headers")
+
+	if p.setOrigin(h, req.Header.Get("Origin")) {
+		h.Set("Access-Control-Allow-Methods", p.methods)
+
+		headers := p.headers
+		if headers == "*" {
+			headers = req.Header.Get("Access-Control-Request-Headers")
+		}
+		if headers != "" {
+			h.Set("Access-Control-Allow-Headers", headers)
+		}
+		if p.maxAge != "" {
+			h.Set("Access-Control-Max-Age", p.maxAge)
+		}
+	}
+
+	resp.WriteHeader(http.StatusNoContent)
+}
+
+// Apply adds the CORS headers to the response of an allowed origin.  The
+// response depends on the Origin, so caches are told with Vary even when the
+// request has none.  A policy for every origin answers every request the
+// same way, so its responses don't vary.
+func (p *Policy) Apply(resp http.ResponseWriter, req *http.Request) {
+	h := resp.Header()
+	if !p.all {
+		h.Add("Vary", "Origin")
+	}
+
+	origin := req.Header.Get("Origin")
+	if origin == "" && !p.all {
+		return
+	}
+
+	if p.setOrigin(h, origin) && p.expose != "" {
+		h.Set("Access-Control-Expose-Headers", p.expose)
+	}
+}
+
+// setOrigin sets the allowed origin and credentials headers if the origin is
+// allowed.
+func (p *Policy) setOrigin(h http.Header, origin string) bool {
+	if !p.allowed(origin) {
+		return false
+	}
+
+	if p.all {
+		h.Set("Access-Control-Allow-Origin", "*")
+	} else {
+		h.Set("Access-Control-Allow-Origin", origin)
+	}
+	if p.config.Credentials {
+		h.Set("Access-Control-Allow-Credentials", "true")
+	}
+
+	return true
+}
+
+func (p *Policy) allowed(origin string) bool {
+	if p.all {
+		return true
+	}
+
+	origin = strings.ToLower(origin)
+	if slices.Contains(p.exact, origin) {
+		return true
+	}
+
+	for _, re := range p.patterns {
+		if re.MatchString(origin) {
+			return true
+		}
+	}
+
+	return false
+}
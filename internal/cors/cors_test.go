@@ -0,0 +1,165 @@
+// SPDX-FileCopyrightText: 2024 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package cors
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func preflight(p *Policy, origin string, headers string) http.Header {
+	req := httptest.NewRequest("OPTIONS", "/", nil)
+	req.Header.Set("Origin", origin)
+	req.Header.Set("Access-Control-Request-Method", "PUT")
+	if headers != "" {
+		req.Header.Set("Access-Control-Request-Headers", headers)
+	}
+
+	resp := httptest.NewRecorder()
+	p.Preflight(resp, req)
+	return resp.Header()
+}
+
+func TestOrigins(t *testing.T) {
+	p, err := New(WithConfig(Config{
+		Origins:        []string{"https://Tools.example.com", "https://*.example.net"},
+		OriginPatterns: []string{`https://[a-z]+\.example\.org(:\d+)?`},
+	}))
+	require.NoError(t, err)
+
+	tests := []struct {
+		origin  string
+		allowed bool
+	}{
+		{origin: "https://tools.example.com", allowed: true},
+		{origin: "https://TOOLS.example.com", allowed: true},
+		{origin: "https://other.example.com"},
+		{origin: "https://a.example.net", allowed: true},
+		{origin: "https://example.net"},
+		{origin: "https://a.example.net.evil.com"},
+		{origin: "https://dash.example.org:8443", allowed: true},
+		{origin: "https://dash.example.org.evil.com"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.origin, func(t *testing.T) {
+			h := preflight(p, tc.origin, "")
+			if tc.allowed {
+				assert.Equal(t, tc.origin, h.Get("Access-Control-Allow-Origin"))
+				assert.Equal(t, "GET, HEAD, POST", h.Get("Access-Control-Allow-Methods"))
+			} else {
+				assert.Empty(t, h.Get("Access-Control-Allow-Origin"))
+				assert.Empty(t, h.Get("Access-Control-Allow-Methods"))
+			}
+			assert.Contains(t, h.Values("Vary"), "Origin")
+		})
+	}
+}
+
+func TestPreflight(t *testing.T) {
+	p, err := New(WithConfig(Config{
+		Origins:     []string{"https://tools.example.com"},
+		Methods:     []string{"get", "put"},
+		Headers:     []string{"authorization", "x-request-id"},
+		Credentials: true,
+		MaxAge:      10 * time.Minute,
+	}))
+	require.NoError(t, err)
+
+	h := preflight(p, "https://tools.example.com", "")
+	assert.Equal(t, "https://tools.example.com", h.Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "GET, PUT", h.Get("Access-Control-Allow-Methods"))
+	assert.Equal(t, "Authorization, X-Request-Id", h.Get("Access-Control-Allow-Headers"))
+	assert.Equal(t, "true", h.Get("Access-Control-Allow-Credentials"))
+	assert.Equal(t, "600", h.Get("Access-Control-Max-Age"))
+
+	assert.Empty(t, preflight(p, "https://evil.example.com", "").Get("Access-Control-Allow-Origin"))
+
+	// Every origin gets the wildcard.
+	p, err = New(WithConfig(Config{Origins: []string{"*"}, Headers: []string{"*"}}))
+	require.NoError(t, err)
+
+	h = preflight(p, "https://tools.example.com", "X-Custom")
+	assert.Equal(t, "*", h.Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "X-Custom", h.Get("Access-Control-Allow-Headers"))
+	assert.Empty(t, h.Get("Access-Control-Allow-Credentials"))
+	assert.Empty(t, h.Get("Access-Control-Max-Age"))
+}
+
+func TestApply(t *testing.T) {
+	p, err := New(WithConfig(Config{
+		Origins:       []string{"https://tools.example.com"},
+		ExposeHeaders: []string{"X-Request-Id"},
+	}))
+	require.NoError(t, err)
+
+	apply := func(origin string) http.Header {
+		req := httptest.NewRequest("GET", "/", nil)
+		if origin != "" {
+			req.Header.Set("Origin", origin)
+		}
+		resp := httptest.NewRecorder()
+		p.Apply(resp, req)
+		return resp.Header()
+	}
+
+	h := apply("https://tools.example.com")
+	assert.Equal(t, "https://tools.example.com", h.Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "X-Request-Id", h.Get("Access-Control-Expose-Headers"))
+	assert.Equal(t, "Origin", h.Get("Vary"))
+
+	h = apply("https://other.example.com")
+	assert.Empty(t, h.Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "Origin", h.Get("Vary"))
+
+	// A cache must not give the response without an origin to the origins.
+	h = apply("")
+	assert.Empty(t, h.Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "Origin", h.Get("Vary"))
+
+	// Every request gets the wildcard, so the response doesn't vary.
+	p, err = New(WithConfig(Config{Origins: []string{"*"}}))
+	require.NoError(t, err)
+
+	for _, origin := range []string{"https://tools.example.com", ""} {
+		h = apply(origin)
+		assert.Equal(t, "*", h.Get("Access-Control-Allow-Origin"))
+		assert.Empty(t, h.Values("Vary"))
+	}
+}
+
+func TestIsPreflight(t *testing.T) {
+	req := httptest.NewRequest("OPTIONS", "/", nil)
+	assert.False(t, IsPreflight(req))
+
+	req.Header.Set("Origin", "https://tools.example.com")
+	assert.False(t, IsPreflight(req))
+
+	req.Header.Set("Access-Control-Request-Method", "GET")
+	assert.True(t, IsPreflight(req))
+
+	req.Method = "GET"
+	assert.False(t, IsPreflight(req))
+}
+
+func TestInvalidConfig(t *testing.T) {
+	tests := []Config{
+		{Origins: []string{""}},
+		{OriginPatterns: []string{"("}},
+		{Origins: []string{"*"}, Credentials: true},
+	}
+
+	for _, c := range tests {
+		_, err := New(WithConfig(c))
+		assert.ErrorIs(t, err, ErrInvalidConfig)
+	}
+
+	assert.False(t, Config{}.Enabled())
+	assert.True(t, Config{OriginPatterns: []string{".*"}}.Enabled())
+}
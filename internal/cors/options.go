@@ -0,0 +1,87 @@
+// SPDX-FileCopyrightText: 2024 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package cors
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+var ErrInvalidConfig = errors.New("invalid cors configuration")
+
+type Option interface {
+	apply(*Policy) error
+}
+
+type optionFunc func(*Policy) error
+
+func (f optionFunc) apply(p *Policy) error {
+	return f(p)
+}
+
+// WithConfig sets the policy.  The origin patterns must be valid regular
+// expressions, and credentials can't be allowed for every origin.
+func WithConfig(c Config) Option {
+	return optionFunc(func(p *Policy) error {
+		var (
+			exact    []string
+			patterns []*regexp.Regexp
+			all      bool
+		)
+
+		for _, origin := range c.Origins {
+			origin = strings.ToLower(origin)
+			switch {
+			case origin == "":
+				return fmt.Errorf("%w: empty origin", ErrInvalidConfig)
+			case origin == "*":
+				all = true
+			case strings.Contains(origin, "*"):
+				parts := strings.Split(origin, "*")
+				for i := range parts {
+					parts[i] = regexp.QuoteMeta(parts[i])
+				}
+				patterns = append(patterns, regexp.MustCompile("^"+strings.Join(parts, "[^/]*")+"$"))
+			default:
+				exact = append(exact, origin)
+			}
+		}
+
+		if all && c.Credentials {
+			return fmt.Errorf("%w: credentials can't be allowed for the * origin", ErrInvalidConfig)
+		}
+
+		for _, pattern := range c.OriginPatterns {
+			re, err := regexp.Compile("^(?:" + pattern + ")$")
+			if err != nil {
+				return fmt.Errorf("%w: origin pattern '%s': %w", ErrInvalidConfig, pattern, err)
+			}
+			patterns = append(patterns, re)
+		}
+
+		methods := make([]string, 0, len(c.Methods))
+		for _, m := range c.Methods {
+			methods = append(methods, strings.ToUpper(m))
+		}
+		c.Methods = methods
+
+		headers := make([]string, 0, len(c.Headers))
+		for _, h := range c.Headers {
+			if h != "*" {
+				h = http.CanonicalHeaderKey(h)
+			}
+			headers = append(headers, h)
+		}
+		c.Headers = headers
+
+		p.config = c
+		p.all = all
+		p.exact = exact
+		p.patterns = patterns
+		return nil
+	})
+}
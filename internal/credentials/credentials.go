@@ -0,0 +1,343 @@
+// SPDX-FileCopyrightText: 2024 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package credentials
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/xmidt-org/arrange/arrangehttp"
+	"github.com/xmidt-org/eventor"
+)
+
+const (
+	// DefaultRefreshBefore is how long before the token expires that a new
+	// token is fetched if Config.RefreshBefore is not set.
+	DefaultRefreshBefore = time.Minute
+
+	// defaultLifetime is used when the token endpoint does not provide an
+	// expiration.
+	defaultLifetime = 5 * time.Minute
+
+	// maxResponseSize limits how much of the token response is read.
+	maxResponseSize = 1 << 20
+
+	// minRetry and maxRetry bound the backoff between failed fetches.
+	minRetry = time.Second
+	maxRetry = time.Minute
+
+	// refreshTimeout limits the fetches made in the background, which don't
+	// have a request to end them.
+	refreshTimeout = 30 * time.Second
+)
+
+var (
+	// ErrInvalidConfig is returned when the config is invalid.
+	ErrInvalidConfig = errors.New("invalid config")
+
+	// ErrFetchFailed is returned when a token could not be obtained.
+	ErrFetchFailed = errors.New("token fetch failed")
+)
+
+// Config holds the configuration for obtaining outbound credentials using the
+// OAuth2 client credentials grant.
+type Config struct {
+	// TokenURL is the URL of the token endpoint.  If this is empty, no
+	// credentials are added to outbound requests.
+	TokenURL string
+
+	// ClientID is the client id to authenticate with.
+	ClientID string
+
+	// ClientSecret is the client secret to authenticate with.
+	ClientSecret string
+
+	// Scopes are the optional scopes to request.
+	Scopes []string
+
+	// Audience is the optional audience to request.
+	Audience string
+
+	// RefreshBefore is how long before the token expires that a new token is
+	// fetched.  The current token is used while the new one is fetched, and
+	// until it expires if the fetches fail.  Failed fetches are retried with
+	// a backoff.  Defaults to DefaultRefreshBefore.
+	RefreshBefore time.Duration
+
+	// HTTPClient is the configuration for the http client used to fetch tokens.
+	HTTPClient arrangehttp.ClientConfig
+}
+
+// Provider obtains and caches outbound credentials.
+type Provider struct {
+	config              Config
+	client              *http.Client
+	now                 func() time.Time
+	fetchEventListeners eventor.Eventor[FetchEventListener]
+
+	m         sync.Mutex
+	token     string
+	expires   time.Time
+	refreshAt time.Time
+
+	// refreshing is closed when the fetch in flight is done, and is nil when
+	// there is none.
+	refreshing chan struct{}
+
+	// err is the error of the last fetch, and failures counts the fetches
+	// that failed in a row.
+	err      error
+	failures int
+}
+
+type tokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int64  `json:"expires_in"`
+}
+
+// New creates a new Provider.
+func New(opts ...Option) (*Provider, error) {
+	p := Provider{
+		now: time.Now,
+	}
+
+	opts = append(opts, validate())
+
+	for _, opt := range opts {
+		if opt != nil {
+			if err := opt.apply(&p); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if p.config.RefreshBefore <= 0 {
+		p.config.RefreshBefore = DefaultRefreshBefore
+	}
+
+	if p.client == nil && p.Enabled() {
+		client, err := p.config.HTTPClient.NewClient()
+		if err != nil {
+			return nil, err
+		}
+		p.client = client
+	}
+
+	return &p, nil
+}
+
+// Enabled returns true if the provider is configured to add credentials.
+func (p *Provider) Enabled() bool {
+	return p.config.TokenURL != ""
+}
+
+// Token returns a valid access token, fetching a new one if the cached token
+// is missing or about to expire.  Only one fetch is made at a time.  A token
+// that is about to expire is returned while the new one is fetched in the
+// background, so requests only wait for a fetch when there is no valid token.
+func (p *Provider) Token(ctx context.Context) (string, error) {
+	if !p.Enabled() {
+		return "", nil
+	}
+
+	for {
+		p.m.Lock()
+		now := p.now()
+		valid := p.token != "" && now.Before(p.expires)
+
+		switch {
+		case now.Before(p.refreshAt), valid && p.refreshing != nil:
+			token, err := p.token, p.err
+			p.m.Unlock()
+			if valid {
+				return token, nil
+			}
+			// Still backing off from the failed fetch.
+			return "", err
+
+		case p.refreshing != nil:
+			done := p.refreshing
+			p.m.Unlock()
+			select {
+			case <-done:
+				continue
+			case <-ctx.Done():
+				return "", ctx.Err()
+			}
+		}
+
+		done := make(chan struct{})
+		p.refreshing = done
+		token := p.token
+		p.m.Unlock()
+
+		if valid {
+			go func() {
+				ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), refreshTimeout)
+				defer cancel()
+				_ = p.refresh(ctx, done)
+			}()
+			return token, nil
+		}
+
+		if err := p.refresh(ctx, done); err != nil {
+			return "", err
+		}
+	}
+}
+
+// refresh fetches a new token and closes done once it is stored.  Failed
+// fetches are retried after a backoff that doubles with every failure.
+func (p *Provider) refresh(ctx context.Context, done chan struct{}) error {
+	token, lifetime, err := p.fetch(ctx)
+
+	p.m.Lock()
+	defer p.m.Unlock()
+	defer close(done)
+
+	p.refreshing = nil
+	now := p.now()
+
+	if err != nil {
+		p.err = err
+		p.failures++
+		p.refreshAt = now.Add(min(minRetry<<min(p.failures-1, 16), maxRetry))
+		return err
+	}
+
+	p.token = token
+	p.expires = now.Add(lifetime)
+	// Short lived tokens are refreshed halfway through their lifetime.
+	p.refreshAt = p.expires.Add(-min(p.config.RefreshBefore, lifetime/2))
+	p.err = nil
+	p.failures = 0
+
+	return nil
+}
+
+// RoundTripper decorates next so that every request carries the access token
+// in the Authorization header.  Requests that already have an Authorization
+// header are sent as is.
+func (p *Provider) RoundTripper(next http.RoundTripper) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+
+	if !p.Enabled() {
+		return next
+	}
+
+	return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		if req.Header.Get("Authorization") != "" {
+			return next.RoundTrip(req)
+		}
+
+		token, err := p.Token(req.Context())
+		if err != nil {
+			if req.Body != nil {
+				req.Body.Close()
+			}
+			return nil, err
+		}
+
+		req = req.Clone(req.Context())
+		req.Header.Set("Authorization", "Bearer "+token)
+
+		return next.RoundTrip(req)
+	})
+}
+
+// ClientOption returns an arrangehttp option that adds the credentials to
+// clients built from an arrangehttp.ClientConfig.
+func (p *Provider) ClientOption() arrangehttp.Option[http.Client] {
+	return arrangehttp.ClientMiddleware(p.RoundTripper)
+}
+
+// fetch requests a new token from the token endpoint.  It runs without the
+// lock, and only for the caller that started the refresh.
+func (p *Provider) fetch(ctx context.Context) (token string, lifetime time.Duration, err error) {
+	var e FetchEvent
+
+	e.At = p.now()
+	defer func() {
+		e.Duration = p.now().Sub(e.At)
+		e.Err = err
+		p.fetchEventListeners.Visit(func(listener FetchEventListener) {
+			listener.OnFetchEvent(e)
+		})
+	}()
+
+	form := url.Values{
+		"grant_type": {"client_credentials"},
+	}
+	if len(p.config.Scopes) > 0 {
+		form.Set("scope", strings.Join(p.config.Scopes, " "))
+	}
+	if p.config.Audience != "" {
+		form.Set("audience", p.config.Audience)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.config.TokenURL,
+		strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", 0, errors.Join(ErrFetchFailed, err)
+	}
+
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	req.SetBasicAuth(url.QueryEscape(p.config.ClientID), url.QueryEscape(p.config.ClientSecret))
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return "", 0, errors.Join(ErrFetchFailed, err)
+	}
+	defer resp.Body.Close()
+
+	e.StatusCode = resp.StatusCode
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseSize))
+	if err != nil {
+		return "", 0, errors.Join(ErrFetchFailed, err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return "", 0, fmt.Errorf("%w: unexpected status code %d", ErrFetchFailed, resp.StatusCode)
+	}
+
+	var tr tokenResponse
+	if err = json.Unmarshal(body, &tr); err != nil {
+		return "", 0, errors.Join(ErrFetchFailed, err)
+	}
+
+	if tr.AccessToken == "" {
+		return "", 0, fmt.Errorf("%w: no access_token in response", ErrFetchFailed)
+	}
+
+	if tr.TokenType != "" && !strings.EqualFold(tr.TokenType, "bearer") {
+		return "", 0, fmt.Errorf("%w: unsupported token_type '%s'", ErrFetchFailed, tr.TokenType)
+	}
+
+	lifetime = defaultLifetime
+	if tr.ExpiresIn > 0 {
+		lifetime = time.Duration(tr.ExpiresIn) * time.Second
+	}
+	e.Expiration = e.At.Add(lifetime)
+
+	return tr.AccessToken, lifetime, nil
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
@@ -0,0 +1,300 @@
+// SPDX-FileCopyrightText: 2024 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package credentials
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xmidt-org/arrange/arrangehttp"
+	"go.uber.org/zap/zapcore"
+)
+
+type tokenServer struct {
+	*httptest.Server
+	fetches atomic.Int32
+	fail    atomic.Bool
+}
+
+func newTokenServer(t *testing.T, expiresIn int) *tokenServer {
+	ts := &tokenServer{}
+	ts.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, secret, ok := r.BasicAuth()
+		assert.True(t, ok)
+		assert.Equal(t, "client", id)
+		assert.Equal(t, "secret", secret)
+		assert.NoError(t, r.ParseForm())
+		assert.Equal(t, "client_credentials", r.PostForm.Get("grant_type"))
+		assert.Equal(t, "a b", r.PostForm.Get("scope"))
+
+		if ts.fail.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		n := ts.fetches.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"access_token":"token-%d","token_type":"Bearer","expires_in":%d}`, n, expiresIn)
+	}))
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+type clock struct {
+	now time.Time
+}
+
+func (c *clock) Now() time.Time { return c.now }
+
+func newProvider(t *testing.T, url string, c *clock, events *[]FetchEvent) *Provider {
+	p, err := New(
+		WithConfig(Config{
+			TokenURL:      url,
+			ClientID:      "client",
+			ClientSecret:  "secret",
+			Scopes:        []string{"a", "b"},
+			RefreshBefore: time.Minute,
+		}),
+		AddFetchEventListener(FetchEventListenerFunc(func(e FetchEvent) {
+			*events = append(*events, e)
+		})),
+	)
+	require.NoError(t, err)
+	p.now = c.Now
+	return p
+}
+
+// waitRefresh waits for the fetch in flight, if any.
+func waitRefresh(p *Provider) {
+	p.m.Lock()
+	done := p.refreshing
+	p.m.Unlock()
+	if done != nil {
+		<-done
+	}
+}
+
+func TestToken(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	ts := newTokenServer(t, 3600)
+	c := &clock{now: time.Now()}
+	var events []FetchEvent
+	p := newProvider(t, ts.URL, c, &events)
+
+	token, err := p.Token(context.Background())
+	require.NoError(err)
+	assert.Equal("token-1", token)
+	require.Len(events, 1)
+	assert.NoError(events[0].Err)
+	assert.Equal(http.StatusOK, events[0].StatusCode)
+
+	// Cached.
+	c.now = c.now.Add(30 * time.Minute)
+	token, err = p.Token(context.Background())
+	require.NoError(err)
+	assert.Equal("token-1", token)
+	assert.Equal(int32(1), ts.fetches.Load())
+
+	// Inside the refresh window, a failed refresh keeps the current token.
+	c.now = c.now.Add(29*time.Minute + 30*time.Second)
+	ts.fail.Store(true)
+	token, err = p.Token(context.Background())
+	require.NoError(err)
+	assert.Equal("token-1", token)
+	waitRefresh(p)
+	require.Len(events, 2)
+	assert.ErrorIs(events[1].Err, ErrFetchFailed)
+	assert.Equal(http.StatusServiceUnavailable, events[1].StatusCode)
+
+	// Once the token expires the failure is returned.
+	c.now = c.now.Add(time.Minute)
+	_, err = p.Token(context.Background())
+	assert.ErrorIs(err, ErrFetchFailed)
+
+	// The failure is returned without fetching during the backoff.
+	ts.fail.Store(false)
+	_, err = p.Token(context.Background())
+	assert.ErrorIs(err, ErrFetchFailed)
+	assert.Len(events, 3)
+
+	// A successful refresh replaces the token.
+	c.now = c.now.Add(minRetry << 1)
+	token, err = p.Token(context.Background())
+	require.NoError(err)
+	assert.Equal("token-2", token)
+}
+
+func TestShortLivedToken(t *testing.T) {
+	ts := newTokenServer(t, 60)
+	c := &clock{now: time.Now()}
+	var events []FetchEvent
+	p := newProvider(t, ts.URL, c, &events)
+
+	_, err := p.Token(context.Background())
+	require.NoError(t, err)
+
+	c.now = c.now.Add(29 * time.Second)
+	_, err = p.Token(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, int32(1), ts.fetches.Load())
+
+	c.now = c.now.Add(2 * time.Second)
+	_, err = p.Token(context.Background())
+	require.NoError(t, err)
+	waitRefresh(p)
+	assert.Equal(t, int32(2), ts.fetches.Load())
+}
+
+func TestBackgroundRefresh(t *testing.T) {
+	var (
+		fetches atomic.Int32
+		release = make(chan struct{})
+	)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		n := fetches.Add(1)
+		if n > 1 {
+			<-release
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"access_token":"token-%d","expires_in":3600}`, n)
+	}))
+	defer ts.Close()
+
+	c := &clock{now: time.Now()}
+	var events []FetchEvent
+	p := newProvider(t, ts.URL, c, &events)
+
+	token, err := p.Token(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "token-1", token)
+
+	// The current token is returned while a single fetch is in flight.
+	c.now = c.now.Add(59*time.Minute + 30*time.Second)
+	for range 10 {
+		token, err = p.Token(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, "token-1", token)
+	}
+
+	close(release)
+	waitRefresh(p)
+
+	token, err = p.Token(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "token-2", token)
+	assert.Equal(t, int32(2), fetches.Load())
+}
+
+func TestConcurrentFetch(t *testing.T) {
+	ts := newTokenServer(t, 3600)
+	c := &clock{now: time.Now()}
+	var events []FetchEvent
+	p := newProvider(t, ts.URL, c, &events)
+
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			token, err := p.Token(context.Background())
+			assert.NoError(t, err)
+			assert.Equal(t, "token-1", token)
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, int32(1), ts.fetches.Load())
+}
+
+func TestClientOption(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	ts := newTokenServer(t, 3600)
+	var events []FetchEvent
+	p := newProvider(t, ts.URL, &clock{now: time.Now()}, &events)
+
+	var got []string
+	backend := httptest.NewServer(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		got = append(got, r.Header.Get("Authorization"))
+	}))
+	defer backend.Close()
+
+	client, err := arrangehttp.NewClient(arrangehttp.ClientConfig{}, p.ClientOption())
+	require.NoError(err)
+
+	resp, err := client.Get(backend.URL)
+	require.NoError(err)
+	resp.Body.Close()
+
+	req, err := http.NewRequest(http.MethodGet, backend.URL, nil)
+	require.NoError(err)
+	req.Header.Set("Authorization", "Basic preset")
+	resp, err = client.Do(req)
+	require.NoError(err)
+	resp.Body.Close()
+
+	assert.Equal([]string{"Bearer token-1", "Basic preset"}, got)
+}
+
+func TestDisabled(t *testing.T) {
+	p, err := New(WithConfig(Config{}))
+	require.NoError(t, err)
+	assert.False(t, p.Enabled())
+
+	token, err := p.Token(context.Background())
+	assert.NoError(t, err)
+	assert.Empty(t, token)
+	assert.Equal(t, http.DefaultTransport, p.RoundTripper(nil))
+}
+
+func TestInvalidConfig(t *testing.T) {
+	_, err := New(WithConfig(Config{TokenURL: "http://example.com/token"}))
+	assert.ErrorIs(t, err, ErrInvalidConfig)
+
+	_, err = New(WithConfig(Config{TokenURL: "not a url", ClientID: "client"}))
+	assert.ErrorIs(t, err, ErrInvalidConfig)
+}
+
+func TestFetchEventEncoding(t *testing.T) {
+	at := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	enc := zapcore.NewMapObjectEncoder()
+	require.NoError(t, FetchEvent{
+		At:         at,
+		Duration:   1500 * time.Microsecond,
+		StatusCode: http.StatusOK,
+		Expiration: at.Add(time.Hour),
+	}.MarshalLogObject(enc))
+	assert.Equal(t, map[string]any{
+		"at":          "2024-05-01T12:00:00Z",
+		"duration_ms": 1.5,
+		"status_code": http.StatusOK,
+		"outcome":     "success",
+		"expiration":  "2024-05-01T13:00:00Z",
+	}, enc.Fields)
+
+	enc = zapcore.NewMapObjectEncoder()
+	require.NoError(t, FetchEvent{
+		At:  at,
+		Err: ErrFetchFailed,
+	}.MarshalLogObject(enc))
+	assert.Equal(t, map[string]any{
+		"at":          "2024-05-01T12:00:00Z",
+		"duration_ms": 0.0,
+		"status_code": 0,
+		"outcome":     "failure",
+		"error":       ErrFetchFailed.Error(),
+	}, enc.Fields)
+}
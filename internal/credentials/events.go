@@ -0,0 +1,85 @@
+// SPDX-FileCopyrightText: 2024 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package credentials
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/xmidt-org/skeleton/internal/endpoint"
+	"go.uber.org/zap/zapcore"
+)
+
+// FetchEvent is the event that is sent about a token fetch.
+type FetchEvent struct {
+	// At holds the time when the fetch was started.
+	At time.Time
+
+	// Duration is the time needed to fetch the token.
+	Duration time.Duration
+
+	// StatusCode is the http status code returned by the token endpoint.  It
+	// is zero if no response was received.
+	StatusCode int
+
+	// Expiration is when the fetched token expires.
+	Expiration time.Time
+
+	// Err is the resulting error.
+	Err error
+}
+
+func (e FetchEvent) String() string {
+	buf := strings.Builder{}
+
+	buf.WriteString("credentials.FetchEvent{\n")
+	buf.WriteString(fmt.Sprintf("  At:         %s\n", e.At.Format(time.RFC3339)))
+	buf.WriteString(fmt.Sprintf("  Duration:   %s\n", e.Duration.String()))
+	buf.WriteString(fmt.Sprintf("  StatusCode: %d\n", e.StatusCode))
+	buf.WriteString(fmt.Sprintf("  Expiration: %s\n", e.Expiration.Format(time.RFC3339)))
+	buf.WriteString(fmt.Sprintf("  Err:        %v\n", e.Err))
+	buf.WriteString("}")
+
+	return buf.String()
+}
+
+// MarshalLogObject encodes the event so it can be logged with zap.Object or
+// zap.Inline.  The keys are:
+//
+//	at           string  the fetch time in UTC, RFC 3339 with nanoseconds
+//	duration_ms  number  fractional milliseconds
+//	status_code  number  zero if no response was received
+//	outcome      string  "success" or "failure"
+//	expiration   string  when the token expires, omitted if the fetch failed
+//	error        string  the error message, omitted if there is no error
+func (e FetchEvent) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	enc.AddString("at", e.At.UTC().Format(time.RFC3339Nano))
+	enc.AddFloat64("duration_ms", float64(e.Duration)/float64(time.Millisecond))
+	enc.AddInt("status_code", e.StatusCode)
+	enc.AddString("outcome", endpoint.Outcome(e.Err))
+
+	if e.Err == nil {
+		enc.AddString("expiration", e.Expiration.UTC().Format(time.RFC3339Nano))
+	} else {
+		enc.AddString("error", e.Err.Error())
+	}
+
+	return nil
+}
+
+// FetchEventListener is the interface that must be implemented by types that
+// want to receive FetchEvent notifications.
+type FetchEventListener interface {
+	OnFetchEvent(FetchEvent)
+}
+
+// FetchEventListenerFunc is a function type that implements FetchEventListener.
+// It can be used as an adapter for functions that need to implement the
+// FetchEventListener interface.
+type FetchEventListenerFunc func(FetchEvent)
+
+func (f FetchEventListenerFunc) OnFetchEvent(e FetchEvent) {
+	f(e)
+}
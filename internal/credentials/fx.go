@@ -0,0 +1,55 @@
+// SPDX-FileCopyrightText: 2024 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package credentials
+
+import (
+	"net/http"
+
+	kit "github.com/go-kit/kit/metrics"
+	"github.com/xmidt-org/arrange/arrangehttp"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+)
+
+type telemetryIn struct {
+	fx.In
+
+	Logger   *zap.Logger
+	Counter  kit.Counter   `name:"outbound_token_fetch_count"`
+	Duration kit.Histogram `name:"outbound_token_fetch_duration"`
+}
+
+var Module = fx.Module("credentials",
+	fx.Provide(
+		func(in telemetryIn) *telemetry {
+			return &telemetry{
+				logger:   in.Logger,
+				counter:  in.Counter,
+				duration: in.Duration,
+			}
+		}),
+	fx.Provide(
+		func(cfg Config, t *telemetry) (*Provider, error) {
+			return New(
+				WithConfig(cfg),
+				AddFetchEventListener(t),
+			)
+		},
+	),
+)
+
+// ProvideClientOption adds the outbound credentials to the clients that take
+// their options from the clientName+".options" value group, such as the client
+// built by arrangehttp.ProvideClient(clientName) or the oker webhooks
+// ("oker.webhooks").
+func ProvideClientOption(clientName string) fx.Option {
+	return fx.Provide(
+		fx.Annotate(
+			func(p *Provider) arrangehttp.Option[http.Client] {
+				return p.ClientOption()
+			},
+			fx.ResultTags(`group:"`+clientName+`.options"`),
+		),
+	)
+}
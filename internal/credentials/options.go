@@ -0,0 +1,70 @@
+// SPDX-FileCopyrightText: 2024 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package credentials
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// Option is an interface that is used to apply options to the Provider.
+type Option interface {
+	apply(*Provider) error
+}
+
+type optionFunc func(*Provider) error
+
+func (f optionFunc) apply(p *Provider) error {
+	return f(p)
+}
+
+func WithConfig(c Config) Option {
+	return optionFunc(func(p *Provider) error {
+		p.config = c
+		return nil
+	})
+}
+
+// WithHTTPClient sets the http client used to fetch tokens.  If this is not
+// set, a client is created from Config.HTTPClient.
+func WithHTTPClient(c *http.Client) Option {
+	return optionFunc(func(p *Provider) error {
+		p.client = c
+		return nil
+	})
+}
+
+// AddFetchEventListener adds a listener for token fetch events.  If the
+// optional cancel parameter is provided, it is set to a function that can be
+// used to cancel the listener.
+func AddFetchEventListener(listener FetchEventListener, cancel ...*func()) Option {
+	return optionFunc(func(p *Provider) error {
+		cncl := p.fetchEventListeners.Add(listener)
+		if len(cancel) > 0 && cancel[0] != nil {
+			*cancel[0] = cncl
+		}
+		return nil
+	})
+}
+
+//------------------------------------------------------------------------------
+
+func validate() Option {
+	return optionFunc(func(p *Provider) error {
+		if p.config.TokenURL == "" {
+			return nil
+		}
+
+		if _, err := url.ParseRequestURI(p.config.TokenURL); err != nil {
+			return fmt.Errorf("%w: invalid token url: %w", ErrInvalidConfig, err)
+		}
+
+		if p.config.ClientID == "" {
+			return fmt.Errorf("%w: a client id is required", ErrInvalidConfig)
+		}
+
+		return nil
+	})
+}
@@ -1,13 +1,13 @@
 // SPDX-FileCopyrightText: 2024 Comcast Cable Communications Management, LLC
 // SPDX-License-Identifier: Apache-2.0
 
-package oker
+package credentials
 
 import (
 	"strconv"
-	"time"
 
 	kit "github.com/go-kit/kit/metrics"
+	"github.com/xmidt-org/skeleton/internal/endpoint"
 	"go.uber.org/zap"
 )
 
@@ -17,30 +17,16 @@ type telemetry struct {
 	logger   *zap.Logger
 }
 
-func (t *telemetry) OnOkEvent(e OkEvent) {
-	outcome := "failure"
+func (t *telemetry) OnFetchEvent(e FetchEvent) {
 	if e.Err == nil {
-		outcome = "success"
-	}
-
-	fields := []zap.Field{
-		zap.String("fetched_at", e.At.Format(time.RFC3339)),
-		zap.Duration("duration", e.Duration),
-		zap.Int("status_code", e.StatusCode),
-		zap.String("outcome", outcome),
-		zap.Error(e.Err),
-	}
-
-	if e.Err == nil {
-		t.logger.Info("oking request", fields...)
+		t.logger.Debug("fetched outbound token", zap.Inline(e))
 	} else {
-		t.logger.Error("oking request", fields...)
+		t.logger.Error("failed to fetch outbound token", zap.Inline(e))
 	}
 
 	labels := []string{
-		"outcome", outcome,
+		"outcome", endpoint.Outcome(e.Err),
 		"status_code", strconv.Itoa(e.StatusCode),
-		"partnerid", e.PartnerID,
 	}
 
 	t.counter.With(labels...).Add(1)
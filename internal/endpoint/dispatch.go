@@ -0,0 +1,178 @@
+// SPDX-FileCopyrightText: 2024 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package endpoint
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	kit "github.com/go-kit/kit/metrics"
+	"github.com/go-kit/kit/metrics/discard"
+)
+
+const (
+	// OverflowDrop drops new events when the queue is full.
+	OverflowDrop = "drop"
+
+	// OverflowBlock blocks the request until there is room in the queue or
+	// the events are stopped.
+	OverflowBlock = "block"
+)
+
+// ErrInvalidDispatch is returned when the Dispatch configuration is invalid.
+var ErrInvalidDispatch = errors.New("invalid dispatch config")
+
+// Dispatch configures how events are delivered to the listeners.
+type Dispatch struct {
+	// QueueSize is the number of events that can be waiting to be delivered.
+	// If this is zero, events are delivered synchronously as part of the
+	// request.
+	QueueSize int
+
+	// Workers is the number of goroutines delivering events.  Listeners may be
+	// called concurrently if this is more than one.  Defaults to 1.
+	Workers int
+
+	// Overflow is what happens when the queue is full.  Either "drop" (the
+	// default) or "block".
+	Overflow string
+}
+
+// dispatcher delivers events to the listeners from a bounded queue.
+type dispatcher[E any] struct {
+	queue   chan E
+	block   bool
+	visit   func(E)
+	depth   kit.Gauge
+	dropped kit.Counter
+
+	// stop is closed when the dispatcher is closing, releasing the senders
+	// waiting for room in a full queue.
+	stop     chan struct{}
+	stopOnce sync.Once
+
+	m      sync.RWMutex
+	closed bool
+	done   sync.WaitGroup
+}
+
+func (cfg Dispatch) validate() error {
+	if cfg.QueueSize < 0 || cfg.Workers < 0 {
+		return fmt.Errorf("%w: queue size and workers must not be negative", ErrInvalidDispatch)
+	}
+
+	switch cfg.Overflow {
+	case "", OverflowDrop, OverflowBlock:
+	default:
+		return fmt.Errorf("%w: unknown overflow policy '%s'", ErrInvalidDispatch, cfg.Overflow)
+	}
+
+	return nil
+}
+
+func newDispatcher[E any](cfg Dispatch, visit func(E), depth kit.Gauge, dropped kit.Counter) (*dispatcher[E], error) {
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+
+	if depth == nil {
+		depth = discard.NewGauge()
+	}
+	if dropped == nil {
+		dropped = discard.NewCounter()
+	}
+
+	d := dispatcher[E]{
+		queue:   make(chan E, cfg.QueueSize),
+		block:   cfg.Overflow == OverflowBlock,
+		visit:   visit,
+		depth:   depth,
+		dropped: dropped,
+		stop:    make(chan struct{}),
+	}
+
+	workers := max(cfg.Workers, 1)
+	d.done.Add(workers)
+	for range workers {
+		go d.work()
+	}
+
+	return &d, nil
+}
+
+func (d *dispatcher[E]) work() {
+	defer d.done.Done()
+
+	for e := range d.queue {
+		d.depth.Set(float64(len(d.queue)))
+		d.visit(e)
+	}
+}
+
+// dispatch queues the event.  Events that arrive after the dispatcher is
+// closed, or that are waiting for room in the queue when it closes, are
+// dropped.
+func (d *dispatcher[E]) dispatch(e E) {
+	d.m.RLock()
+	defer d.m.RUnlock()
+
+	if d.closed {
+		d.dropped.Add(1)
+		return
+	}
+
+	if d.block {
+		select {
+		case d.queue <- e:
+		default:
+			// Only the senders waiting on a full queue give up when the
+			// dispatcher closes.
+			select {
+			case d.queue <- e:
+			case <-d.stop:
+				d.dropped.Add(1)
+				return
+			}
+		}
+	} else {
+		select {
+		case d.queue <- e:
+		default:
+			d.dropped.Add(1)
+			return
+		}
+	}
+
+	d.depth.Set(float64(len(d.queue)))
+}
+
+// close stops accepting events and waits for the queued events to be
+// delivered or the context to end.
+func (d *dispatcher[E]) close(ctx context.Context) error {
+	// The blocked senders hold the read lock, so they have to give up
+	// before the queue can be closed.
+	d.stopOnce.Do(func() { close(d.stop) })
+
+	d.m.Lock()
+	if !d.closed {
+		d.closed = true
+		close(d.queue)
+	}
+	d.m.Unlock()
+
+	flushed := make(chan struct{})
+	go func() {
+		d.done.Wait()
+		close(flushed)
+	}()
+
+	select {
+	case <-flushed:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,180 @@
+// SPDX-FileCopyrightText: 2024 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package endpoint
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/metrics/generic"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// gatedListener blocks each event until the gate is opened.
+type gatedListener struct {
+	gate chan struct{}
+
+	m      sync.Mutex
+	events []int
+}
+
+func (l *gatedListener) onEvent(e int) {
+	<-l.gate
+	l.m.Lock()
+	defer l.m.Unlock()
+	l.events = append(l.events, e)
+}
+
+func (l *gatedListener) count() int {
+	l.m.Lock()
+	defer l.m.Unlock()
+	return len(l.events)
+}
+
+func newAsyncEvents(t *testing.T, d Dispatch, l *gatedListener) (*Events[int], *generic.Gauge, *generic.Counter) {
+	depth := generic.NewGauge("depth")
+	dropped := generic.NewCounter("dropped")
+
+	var ev Events[int]
+	ev.Add(l.onEvent)
+	require.NoError(t, ev.Async(d, depth, dropped))
+
+	return &ev, depth, dropped
+}
+
+func TestDispatchDrop(t *testing.T) {
+	l := &gatedListener{gate: make(chan struct{})}
+	ev, depth, dropped := newAsyncEvents(t, Dispatch{QueueSize: 2}, l)
+
+	// The sender does not wait on the listener.  The worker holds one event,
+	// the queue holds two and the rest are dropped.
+	for i := range 5 {
+		ev.Send(i)
+	}
+
+	assert.Eventually(t, func() bool {
+		return dropped.Value() >= 2
+	}, time.Second, time.Millisecond)
+	assert.LessOrEqual(t, depth.Value(), 2.0)
+
+	close(l.gate)
+	require.NoError(t, ev.Stop(context.Background()))
+
+	assert.Equal(t, 5, l.count()+int(dropped.Value()))
+	assert.Equal(t, 0.0, depth.Value())
+
+	// Events after stopping are dropped.
+	before := dropped.Value()
+	ev.Send(5)
+	assert.Equal(t, before+1, dropped.Value())
+}
+
+func TestDispatchBlock(t *testing.T) {
+	l := &gatedListener{gate: make(chan struct{})}
+	ev, _, dropped := newAsyncEvents(t, Dispatch{
+		QueueSize: 1,
+		Workers:   2,
+		Overflow:  OverflowBlock,
+	}, l)
+
+	var wg sync.WaitGroup
+	for i := range 5 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ev.Send(i)
+		}()
+	}
+
+	close(l.gate)
+	wg.Wait()
+	require.NoError(t, ev.Stop(context.Background()))
+
+	assert.Equal(t, 5, l.count())
+	assert.Equal(t, 0.0, dropped.Value())
+}
+
+func TestDispatchBlockStop(t *testing.T) {
+	l := &gatedListener{gate: make(chan struct{})}
+	ev, _, dropped := newAsyncEvents(t, Dispatch{
+		QueueSize: 1,
+		Overflow:  OverflowBlock,
+	}, l)
+
+	// The worker holds the first event and the second fills the queue, so
+	// the third waits for room.
+	ev.Send(1)
+	ev.Send(2)
+
+	sent := make(chan struct{})
+	go func() {
+		defer close(sent)
+		ev.Send(3)
+	}()
+
+	// The blocked sender holds the read lock.
+	require.Eventually(t, func() bool {
+		if ev.dispatcher.m.TryLock() {
+			ev.dispatcher.m.Unlock()
+			return false
+		}
+		return true
+	}, time.Second, time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, ev.Stop(ctx), context.DeadlineExceeded)
+
+	select {
+	case <-sent:
+	case <-time.After(time.Second):
+		t.Fatal("the blocked sender wasn't released")
+	}
+	assert.Equal(t, 1.0, dropped.Value())
+
+	close(l.gate)
+	assert.NoError(t, ev.Stop(context.Background()))
+	assert.Equal(t, 2, l.count())
+}
+
+func TestDispatchStopTimeout(t *testing.T) {
+	l := &gatedListener{gate: make(chan struct{})}
+	ev, _, _ := newAsyncEvents(t, Dispatch{QueueSize: 1}, l)
+
+	ev.Send(1)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, ev.Stop(ctx), context.DeadlineExceeded)
+
+	close(l.gate)
+	assert.NoError(t, ev.Stop(context.Background()))
+	assert.Equal(t, 1, l.count())
+}
+
+func TestDispatchSync(t *testing.T) {
+	l := &gatedListener{gate: make(chan struct{})}
+	close(l.gate)
+	ev, _, _ := newAsyncEvents(t, Dispatch{}, l)
+
+	ev.Send(1)
+	assert.Equal(t, 1, l.count())
+	assert.NoError(t, ev.Stop(context.Background()))
+}
+
+func TestDispatchInvalid(t *testing.T) {
+	tests := []Dispatch{
+		{QueueSize: 1, Overflow: "invalid"},
+		{QueueSize: 1, Workers: -1},
+		{QueueSize: -1},
+	}
+
+	for _, tc := range tests {
+		var ev Events[int]
+		assert.ErrorIs(t, ev.Async(tc, nil, nil), ErrInvalidDispatch)
+	}
+}
@@ -0,0 +1,39 @@
+// SPDX-FileCopyrightText: 2024 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+// Package endpoint holds the pieces shared by the http endpoints of the
+// service: option plumbing, typed event delivery, a standard telemetry
+// listener and fx wiring.  An endpoint only needs to supply its handler and
+// its event type.
+package endpoint
+
+import "net/http"
+
+// Handler serves a request and fills in the event that describes it.
+type Handler[E any] func(resp http.ResponseWriter, req *http.Request, event *E)
+
+// Endpoint is an http.Handler that serves requests with a Handler and sends
+// the resulting events to its listeners.
+type Endpoint[E any] struct {
+	Events[E]
+
+	handler Handler[E]
+}
+
+// New creates an endpoint that serves requests with the handler.
+func New[E any](handler Handler[E]) *Endpoint[E] {
+	return &Endpoint[E]{
+		handler: handler,
+	}
+}
+
+// ServeHTTP calls the handler and sends the event when it returns, even if
+// the handler panics.
+func (ep *Endpoint[E]) ServeHTTP(resp http.ResponseWriter, req *http.Request) {
+	var e E
+	defer func() {
+		ep.Send(e)
+	}()
+
+	ep.handler(resp, req, &e)
+}
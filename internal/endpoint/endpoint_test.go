@@ -0,0 +1,182 @@
+// SPDX-FileCopyrightText: 2024 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package endpoint
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	kit "github.com/go-kit/kit/metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xmidt-org/skeleton/internal/log"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+type testEvent struct {
+	Path       string
+	StatusCode int
+	Duration   time.Duration
+	Err        error
+	TraceID    string
+	SpanID     string
+}
+
+func (e testEvent) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	enc.AddString("path", e.Path)
+	enc.AddInt("status_code", e.StatusCode)
+	return nil
+}
+
+func (e testEvent) Result() Result {
+	return Result{
+		Err:      e.Err,
+		Duration: e.Duration,
+		Labels:   []string{"status_code", strconv.Itoa(e.StatusCode)},
+		TraceID:  e.TraceID,
+		SpanID:   e.SpanID,
+	}
+}
+
+type testServer struct {
+	name string
+}
+
+func withName(name string) Option[testServer] {
+	return OptionFunc[testServer](func(s *testServer) error {
+		s.name = name
+		return nil
+	})
+}
+
+func TestApply(t *testing.T) {
+	var s testServer
+	require.NoError(t, Apply(&s, withName("a"), nil, withName("b")))
+	assert.Equal(t, "b", s.name)
+
+	errUnknown := errors.New("unknown")
+	err := Apply(&s,
+		OptionFunc[testServer](func(*testServer) error { return errUnknown }),
+		withName("c"),
+	)
+	assert.ErrorIs(t, err, errUnknown)
+	assert.Equal(t, "b", s.name)
+}
+
+func TestEndpoint(t *testing.T) {
+	ep := New(func(resp http.ResponseWriter, req *http.Request, e *testEvent) {
+		e.Path = req.URL.Path
+		if req.URL.Path == "/panic" {
+			panic(http.ErrAbortHandler)
+		}
+		e.StatusCode = http.StatusTeapot
+		resp.WriteHeader(e.StatusCode)
+	})
+
+	var events []testEvent
+	cancel := ep.Add(func(e testEvent) {
+		events = append(events, e)
+	})
+
+	resp := httptest.NewRecorder()
+	ep.ServeHTTP(resp, httptest.NewRequest("GET", "/ok", nil))
+	assert.Equal(t, http.StatusTeapot, resp.Code)
+	require.Len(t, events, 1)
+	assert.Equal(t, testEvent{Path: "/ok", StatusCode: http.StatusTeapot}, events[0])
+
+	// The event is sent even if the handler panics.
+	assert.Panics(t, func() {
+		ep.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/panic", nil))
+	})
+	require.Len(t, events, 2)
+	assert.Equal(t, "/panic", events[1].Path)
+
+	cancel()
+	ep.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/ok", nil))
+	assert.Len(t, events, 2)
+}
+
+// recorder is a counter and histogram that records the values by labels.
+type recorder struct {
+	labels []string
+	values map[string][]float64
+}
+
+func newRecorder() recorder {
+	return recorder{values: make(map[string][]float64)}
+}
+
+func (r recorder) with(labelValues ...string) recorder {
+	return recorder{
+		labels: append(append([]string{}, r.labels...), labelValues...),
+		values: r.values,
+	}
+}
+
+func (r recorder) record(v float64) {
+	key := strings.Join(r.labels, ",")
+	r.values[key] = append(r.values[key], v)
+}
+
+type recordingCounter struct{ recorder }
+
+func (c recordingCounter) With(lvs ...string) kit.Counter {
+	return recordingCounter{c.with(lvs...)}
+}
+
+func (c recordingCounter) Add(v float64) {
+	c.record(v)
+}
+
+type recordingHistogram struct{ recorder }
+
+func (h recordingHistogram) With(lvs ...string) kit.Histogram {
+	return recordingHistogram{h.with(lvs...)}
+}
+
+func (h recordingHistogram) Observe(v float64) {
+	h.record(v)
+}
+
+func TestTelemetry(t *testing.T) {
+	core, logs := observer.New(zapcore.InfoLevel)
+	counter := recordingCounter{newRecorder()}
+	duration := recordingHistogram{newRecorder()}
+
+	tel := NewTelemetry[testEvent]("testing", zap.New(core), counter, duration)
+	tel.OnEvent(testEvent{Path: "/ok", StatusCode: http.StatusOK, Duration: 5 * time.Millisecond})
+	tel.OnEvent(testEvent{Path: "/ok", StatusCode: http.StatusInternalServerError, Err: errors.New("boom")})
+
+	entries := logs.All()
+	require.Len(t, entries, 2)
+	assert.Equal(t, zapcore.InfoLevel, entries[0].Level)
+	assert.Equal(t, "testing", entries[0].Message)
+	assert.Equal(t, "/ok", entries[0].ContextMap()["path"])
+	assert.Equal(t, zapcore.ErrorLevel, entries[1].Level)
+	assert.NotContains(t, entries[0].ContextMap(), log.TraceID)
+
+	assert.Equal(t, map[string][]float64{
+		"outcome,success,status_code,200": {1},
+		"outcome,failure,status_code,500": {1},
+	}, counter.values)
+	assert.Equal(t, map[string][]float64{
+		"outcome,success,status_code,200": {5},
+		"outcome,failure,status_code,500": {0},
+	}, duration.values)
+
+	tel.OnEvent(testEvent{Path: "/ok", StatusCode: http.StatusOK, TraceID: "4bf92f3577b34da6a3ce929d0e0e4736", SpanID: "00f067aa0ba902b7"})
+	fields := logs.All()[2].ContextMap()
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", fields[log.TraceID])
+	assert.Equal(t, "00f067aa0ba902b7", fields[log.SpanID])
+
+	// Nil arguments are allowed.
+	NewTelemetry[testEvent]("testing", nil, nil, nil).OnEvent(testEvent{})
+}
@@ -0,0 +1,71 @@
+// SPDX-FileCopyrightText: 2024 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package endpoint
+
+import (
+	"context"
+
+	kit "github.com/go-kit/kit/metrics"
+	"github.com/xmidt-org/eventor"
+)
+
+// Events is a list of listeners for events of type E.  Events are delivered
+// synchronously unless Async is used.  The zero value is ready to use.
+type Events[E any] struct {
+	listeners  eventor.Eventor[func(E)]
+	dispatcher *dispatcher[E]
+}
+
+// Add adds a listener and returns a function that removes it.
+func (ev *Events[E]) Add(listener func(E)) (cancel func()) {
+	return ev.listeners.Add(listener)
+}
+
+// Async delivers the events from a bounded queue instead of as part of the
+// request.  If the queue size is zero, events are still delivered
+// synchronously.  The metrics may be nil.  Async must be called before any
+// events are sent.
+func (ev *Events[E]) Async(cfg Dispatch, queueDepth kit.Gauge, dropped kit.Counter) error {
+	if err := cfg.validate(); err != nil {
+		return err
+	}
+
+	if cfg.QueueSize == 0 {
+		return nil
+	}
+
+	d, err := newDispatcher(cfg, ev.visit, queueDepth, dropped)
+	if err != nil {
+		return err
+	}
+
+	ev.dispatcher = d
+	return nil
+}
+
+// Send delivers the event to the listeners.
+func (ev *Events[E]) Send(e E) {
+	if ev.dispatcher != nil {
+		ev.dispatcher.dispatch(e)
+		return
+	}
+
+	ev.visit(e)
+}
+
+// Stop stops accepting events and waits for any queued events to be delivered
+// to the listeners or the context to end.
+func (ev *Events[E]) Stop(ctx context.Context) error {
+	if ev.dispatcher == nil {
+		return nil
+	}
+
+	return ev.dispatcher.close(ctx)
+}
+
+func (ev *Events[E]) visit(e E) {
+	ev.listeners.Visit(func(listener func(E)) {
+		listener(e)
+	})
+}
@@ -0,0 +1,23 @@
+// SPDX-FileCopyrightText: 2024 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package endpoint
+
+import (
+	kit "github.com/go-kit/kit/metrics"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+)
+
+// ProvideTelemetry provides a *Telemetry[E] that logs with the message and
+// uses the counter and histogram with the given names.
+func ProvideTelemetry[E Event](message, counter, duration string) fx.Option {
+	return fx.Provide(
+		fx.Annotate(
+			func(logger *zap.Logger, c kit.Counter, d kit.Histogram) *Telemetry[E] {
+				return NewTelemetry[E](message, logger, c, d)
+			},
+			fx.ParamTags(``, `name:"`+counter+`"`, `name:"`+duration+`"`),
+		),
+	)
+}
@@ -0,0 +1,30 @@
+// SPDX-FileCopyrightText: 2024 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package endpoint
+
+// Option configures an endpoint server of type S.
+type Option[S any] interface {
+	Apply(*S) error
+}
+
+// OptionFunc is a function type that implements Option.
+type OptionFunc[S any] func(*S) error
+
+func (f OptionFunc[S]) Apply(s *S) error {
+	return f(s)
+}
+
+// Apply applies the options in order, skipping any nil options.  The first
+// error stops the processing.
+func Apply[S any](s *S, opts ...Option[S]) error {
+	for _, opt := range opts {
+		if opt != nil {
+			if err := opt.Apply(s); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
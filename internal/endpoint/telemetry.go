@@ -0,0 +1,108 @@
+// SPDX-FileCopyrightText: 2024 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package endpoint
+
+import (
+	"time"
+
+	kit "github.com/go-kit/kit/metrics"
+	"github.com/go-kit/kit/metrics/discard"
+	"github.com/xmidt-org/skeleton/internal/log"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	// Success is the outcome of a request without an error.
+	Success = "success"
+
+	// Failure is the outcome of a request with an error.
+	Failure = "failure"
+)
+
+// Outcome returns the outcome of a request with the error.
+func Outcome(err error) string {
+	if err == nil {
+		return Success
+	}
+	return Failure
+}
+
+// Result is what the standard telemetry needs to know about a request.
+type Result struct {
+	// Err is the error of the request, or nil if it succeeded.
+	Err error
+
+	// Duration is the time needed to handle the request.
+	Duration time.Duration
+
+	// Labels are the metric label name and value pairs in addition to the
+	// outcome.
+	Labels []string
+
+	// TraceID and SpanID identify the span of the request, if it was traced.
+	// They are added to the log so it can be joined with the trace.
+	TraceID string
+	SpanID  string
+}
+
+// Event is implemented by event types that can be reported by Telemetry.
+type Event interface {
+	zapcore.ObjectMarshaler
+
+	// Result returns the result of the request the event describes.
+	Result() Result
+}
+
+// Telemetry is a listener that logs each event and records the request
+// count and duration metrics.  The metrics are labeled with the outcome and
+// the labels of the Result.
+type Telemetry[E Event] struct {
+	message  string
+	logger   *zap.Logger
+	counter  kit.Counter
+	duration kit.Histogram
+}
+
+// NewTelemetry creates a Telemetry that logs the events with the message.
+// Nil arguments are replaced with ones that discard their input.
+func NewTelemetry[E Event](message string, logger *zap.Logger, counter kit.Counter, duration kit.Histogram) *Telemetry[E] {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	if counter == nil {
+		counter = discard.NewCounter()
+	}
+	if duration == nil {
+		duration = discard.NewHistogram()
+	}
+
+	return &Telemetry[E]{
+		message:  message,
+		logger:   logger,
+		counter:  counter,
+		duration: duration,
+	}
+}
+
+// OnEvent reports the event.  Failed requests are logged as errors.
+func (t *Telemetry[E]) OnEvent(e E) {
+	r := e.Result()
+
+	fields := []zap.Field{zap.Inline(e)}
+	if r.TraceID != "" {
+		fields = append(fields, zap.String(log.TraceID, r.TraceID), zap.String(log.SpanID, r.SpanID))
+	}
+
+	if r.Err == nil {
+		t.logger.Info(t.message, fields...)
+	} else {
+		t.logger.Error(t.message, fields...)
+	}
+
+	labels := append([]string{"outcome", Outcome(r.Err)}, r.Labels...)
+
+	t.counter.With(labels...).Add(1)
+	t.duration.With(labels...).Observe(float64(r.Duration.Milliseconds()))
+}
@@ -0,0 +1,26 @@
+// SPDX-FileCopyrightText: 2024 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package health
+
+import "go.uber.org/fx"
+
+type RegistryIn struct {
+	fx.In
+	Config Config `optional:"true"`
+
+	// Checks are the checks provided by other modules through the
+	// "health.checks" fx value group.
+	Checks []Check `group:"health.checks"`
+}
+
+var Module = fx.Module("health",
+	fx.Provide(
+		func(in RegistryIn) (*Registry, error) {
+			return New(
+				WithConfig(in.Config),
+				WithChecks(in.Checks...),
+			)
+		},
+	),
+)
@@ -0,0 +1,36 @@
+// SPDX-FileCopyrightText: 2024 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package health
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+)
+
+// Handler serves a probe.  The response is 503 when a critical check fails
+// and 200 otherwise.  The body is the status, or the full Report as JSON when
+// the verbose query parameter is present.
+func (r *Registry) Handler(probe Probe) http.Handler {
+	return http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		report := r.Evaluate(req.Context(), probe)
+
+		code := http.StatusOK
+		if report.Status == StatusFailed {
+			code = http.StatusServiceUnavailable
+		}
+
+		resp.Header().Set("Cache-Control", "no-store")
+		if !req.URL.Query().Has("verbose") {
+			resp.Header().Set("Content-Type", "text/plain; charset=utf-8")
+			resp.WriteHeader(code)
+			_, _ = io.WriteString(resp, report.Status+"\n")
+			return
+		}
+
+		resp.Header().Set("Content-Type", "application/json")
+		resp.WriteHeader(code)
+		_ = json.NewEncoder(resp).Encode(report)
+	})
+}
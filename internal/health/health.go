@@ -0,0 +1,265 @@
+// SPDX-FileCopyrightText: 2024 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+// Package health runs the named checks modules register and reports them on
+// the liveness, readiness and startup probes.
+package health
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Probe selects the probes a check is reported on.  Probes can be combined.
+type Probe uint8
+
+const (
+	// Liveness checks fail when the process needs to be restarted.
+	Liveness Probe = 1 << iota
+
+	// Readiness checks fail when the process should not get traffic.
+	Readiness
+
+	// Startup checks fail until the process has finished starting.  Once the
+	// startup probe passes it is not run again.
+	Startup
+)
+
+const (
+	// StatusOK means every check passed.
+	StatusOK = "ok"
+
+	// StatusDegraded means only non-critical checks failed.  The probe still
+	// passes.
+	StatusDegraded = "degraded"
+
+	// StatusFailed means a critical check failed.
+	StatusFailed = "failed"
+)
+
+var (
+	ErrInvalidCheck = errors.New("invalid health check")
+	ErrTimeout      = errors.New("health check timed out")
+	ErrDraining     = errors.New("draining for shutdown")
+)
+
+// Config is the configuration for the health checks.
+type Config struct {
+	// Timeout is how long a check may run when it does not set its own
+	// timeout.  Defaults to 5s.
+	Timeout time.Duration
+
+	// Cache is how long a result is reused when the check does not set its
+	// own.  Zero runs the check on every probe.
+	Cache time.Duration
+}
+
+// Check is a named health check.
+type Check struct {
+	// Name identifies the check in the reports.  Names must be unique.
+	Name string
+
+	// Check returns an error when the check fails.
+	Check func(context.Context) error
+
+	// Probes are the probes the check is reported on.  Defaults to
+	// Readiness | Startup.
+	Probes Probe
+
+	// Critical checks fail the probe.  Other checks only degrade it.
+	Critical bool
+
+	// Timeout is how long the check may run.  Defaults to Config.Timeout.
+	Timeout time.Duration
+
+	// Cache is how long a result is reused.  Defaults to Config.Cache.
+	Cache time.Duration
+}
+
+// CheckReport is the result of a single check.
+type CheckReport struct {
+	Name      string    `json:"name"`
+	Status    string    `json:"status"`
+	Critical  bool      `json:"critical"`
+	Error     string    `json:"error,omitempty"`
+	Duration  float64   `json:"duration_ms"`
+	CheckedAt time.Time `json:"checked_at"`
+}
+
+// Report is the result of a probe.
+type Report struct {
+	Status string        `json:"status"`
+	Checks []CheckReport `json:"checks"`
+}
+
+// Registry holds the checks and runs them for the probes.
+type Registry struct {
+	config Config
+	checks []*check
+
+	// started holds the passing startup report once there is one.
+	started atomic.Pointer[Report]
+
+	// draining fails the readiness probe once the service is shutting down.
+	draining atomic.Bool
+}
+
+type check struct {
+	Check
+
+	m       sync.Mutex
+	last    CheckReport
+	expires time.Time
+}
+
+// New creates the registry.
+func New(opts ...Option) (*Registry, error) {
+	var r Registry
+
+	for _, opt := range opts {
+		if opt != nil {
+			if err := opt.apply(&r); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if r.config.Timeout <= 0 {
+		r.config.Timeout = 5 * time.Second
+	}
+
+	for _, c := range r.checks {
+		if c.Probes == 0 {
+			c.Probes = Readiness | Startup
+		}
+		if c.Timeout <= 0 {
+			c.Timeout = r.config.Timeout
+		}
+		if c.Cache <= 0 {
+			c.Cache = r.config.Cache
+		}
+	}
+
+	slices.SortFunc(r.checks, func(a, b *check) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+
+	return &r, nil
+}
+
+// Evaluate runs the checks of the probe and reports the results.  The checks
+// run concurrently.  After the startup probe has passed once the passing
+// report is returned without running the checks again.
+func (r *Registry) Evaluate(ctx context.Context, probe Probe) Report {
+	if probe == Startup {
+		if started := r.started.Load(); started != nil {
+			return *started
+		}
+	}
+
+	var selected []*check
+	for _, c := range r.checks {
+		if c.Probes&probe != 0 {
+			selected = append(selected, c)
+		}
+	}
+
+	report := Report{
+		Status: StatusOK,
+		Checks: make([]CheckReport, len(selected)),
+	}
+
+	var wg sync.WaitGroup
+	for i, c := range selected {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			report.Checks[i] = c.run(ctx)
+		}()
+	}
+	wg.Wait()
+
+	for _, cr := range report.Checks {
+		switch {
+		case cr.Status == StatusOK:
+		case cr.Critical:
+			report.Status = StatusFailed
+		case report.Status == StatusOK:
+			report.Status = StatusDegraded
+		}
+	}
+
+	if probe == Readiness && r.draining.Load() {
+		report.Status = StatusFailed
+		report.Checks = append(report.Checks, CheckReport{
+			Name:      "shutdown",
+			Status:    StatusFailed,
+			Critical:  true,
+			Error:     ErrDraining.Error(),
+			CheckedAt: time.Now().UTC(),
+		})
+	}
+
+	if probe == Startup && report.Status != StatusFailed {
+		r.started.CompareAndSwap(nil, &report)
+	}
+
+	return report
+}
+
+// Drain fails the readiness probe from now on so load balancers stop sending
+// traffic before the servers stop.
+func (r *Registry) Drain() {
+	r.draining.Store(true)
+}
+
+// run runs the check unless its cached result is still valid.  Concurrent
+// probes share a single run.
+func (c *check) run(ctx context.Context) CheckReport {
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	now := time.Now()
+	if now.Before(c.expires) {
+		return c.last
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, c.Timeout)
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- c.Check.Check(ctx)
+	}()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = ctx.Err()
+		if errors.Is(err, context.DeadlineExceeded) {
+			err = fmt.Errorf("%w after %s", ErrTimeout, c.Timeout)
+		}
+	}
+
+	c.last = CheckReport{
+		Name:      c.Name,
+		Status:    StatusOK,
+		Critical:  c.Critical,
+		Duration:  float64(time.Since(now)) / float64(time.Millisecond),
+		CheckedAt: now.UTC(),
+	}
+	if err != nil {
+		c.last.Status = StatusFailed
+		c.last.Error = err.Error()
+	}
+	c.expires = now.Add(c.Cache)
+
+	return c.last
+}
@@ -0,0 +1,229 @@
+// SPDX-FileCopyrightText: 2024 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var errBroken = errors.New("broken")
+
+func pass(context.Context) error { return nil }
+func fail(context.Context) error { return errBroken }
+
+func statuses(r Report) map[string]string {
+	got := make(map[string]string, len(r.Checks))
+	for _, c := range r.Checks {
+		got[c.Name] = c.Status
+	}
+	return got
+}
+
+func TestEvaluate(t *testing.T) {
+	tests := []struct {
+		name   string
+		checks []Check
+		probe  Probe
+		status string
+		want   map[string]string
+	}{
+		{
+			name:   "no checks",
+			probe:  Liveness,
+			status: StatusOK,
+			want:   map[string]string{},
+		}, {
+			name: "all pass",
+			checks: []Check{
+				{Name: "a", Check: pass, Critical: true},
+				{Name: "b", Check: pass},
+			},
+			probe:  Readiness,
+			status: StatusOK,
+			want:   map[string]string{"a": StatusOK, "b": StatusOK},
+		}, {
+			name: "non-critical failure",
+			checks: []Check{
+				{Name: "a", Check: pass, Critical: true},
+				{Name: "b", Check: fail},
+			},
+			probe:  Readiness,
+			status: StatusDegraded,
+			want:   map[string]string{"a": StatusOK, "b": StatusFailed},
+		}, {
+			name: "critical failure",
+			checks: []Check{
+				{Name: "a", Check: fail, Critical: true},
+				{Name: "b", Check: fail},
+			},
+			probe:  Readiness,
+			status: StatusFailed,
+			want:   map[string]string{"a": StatusFailed, "b": StatusFailed},
+		}, {
+			name: "only the probe's checks",
+			checks: []Check{
+				{Name: "live", Check: pass, Probes: Liveness},
+				{Name: "ready", Check: fail, Critical: true},
+			},
+			probe:  Liveness,
+			status: StatusOK,
+			want:   map[string]string{"live": StatusOK},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			r, err := New(WithChecks(tc.checks...))
+			require.NoError(t, err)
+
+			report := r.Evaluate(context.Background(), tc.probe)
+			assert.Equal(t, tc.status, report.Status)
+			assert.Equal(t, tc.want, statuses(report))
+		})
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	r, err := New(WithChecks(Check{
+		Name:     "slow",
+		Critical: true,
+		Timeout:  10 * time.Millisecond,
+		Check: func(ctx context.Context) error {
+			<-ctx.Done()
+			time.Sleep(time.Second)
+			return nil
+		},
+	}))
+	require.NoError(t, err)
+
+	report := r.Evaluate(context.Background(), Readiness)
+	assert.Equal(t, StatusFailed, report.Status)
+	require.Len(t, report.Checks, 1)
+	assert.Contains(t, report.Checks[0].Error, ErrTimeout.Error())
+}
+
+func TestCache(t *testing.T) {
+	var calls atomic.Int32
+	counted := func(context.Context) error {
+		calls.Add(1)
+		return nil
+	}
+
+	r, err := New(
+		WithConfig(Config{Cache: time.Hour}),
+		WithChecks(
+			Check{Name: "cached", Check: counted},
+			Check{Name: "uncached", Check: counted, Cache: time.Nanosecond},
+		),
+	)
+	require.NoError(t, err)
+
+	r.Evaluate(context.Background(), Readiness)
+	time.Sleep(time.Millisecond)
+	r.Evaluate(context.Background(), Readiness)
+
+	assert.Equal(t, int32(3), calls.Load())
+}
+
+func TestStartupLatches(t *testing.T) {
+	var ready atomic.Bool
+	r, err := New(WithChecks(Check{
+		Name:     "started",
+		Probes:   Startup,
+		Critical: true,
+		Check: func(context.Context) error {
+			if !ready.Load() {
+				return errBroken
+			}
+			return nil
+		},
+	}))
+	require.NoError(t, err)
+
+	assert.Equal(t, StatusFailed, r.Evaluate(context.Background(), Startup).Status)
+
+	ready.Store(true)
+	assert.Equal(t, StatusOK, r.Evaluate(context.Background(), Startup).Status)
+
+	ready.Store(false)
+	assert.Equal(t, StatusOK, r.Evaluate(context.Background(), Startup).Status)
+}
+
+func TestDrain(t *testing.T) {
+	r, err := New(WithChecks(Check{Name: "a", Check: pass, Critical: true}))
+	require.NoError(t, err)
+
+	assert.Equal(t, StatusOK, r.Evaluate(context.Background(), Readiness).Status)
+
+	r.Drain()
+	report := r.Evaluate(context.Background(), Readiness)
+	assert.Equal(t, StatusFailed, report.Status)
+	assert.Equal(t, map[string]string{"a": StatusOK, "shutdown": StatusFailed}, statuses(report))
+
+	assert.Equal(t, StatusOK, r.Evaluate(context.Background(), Liveness).Status)
+}
+
+func TestInvalidChecks(t *testing.T) {
+	_, err := New(WithChecks(Check{Name: "empty"}))
+	assert.ErrorIs(t, err, ErrInvalidCheck)
+
+	_, err = New(WithChecks(Check{Name: "a", Check: pass}, Check{Name: "a", Check: pass}))
+	assert.ErrorIs(t, err, ErrInvalidCheck)
+}
+
+func TestHandler(t *testing.T) {
+	r, err := New(WithChecks(
+		Check{Name: "a", Check: pass, Critical: true},
+		Check{Name: "b", Check: fail, Critical: true, Probes: Readiness},
+	))
+	require.NoError(t, err)
+
+	resp := httptest.NewRecorder()
+	r.Handler(Liveness).ServeHTTP(resp, httptest.NewRequest("GET", "/livez", nil))
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, "ok\n", resp.Body.String())
+
+	resp = httptest.NewRecorder()
+	r.Handler(Readiness).ServeHTTP(resp, httptest.NewRequest("GET", "/readyz", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, resp.Code)
+	assert.Equal(t, "failed\n", resp.Body.String())
+
+	resp = httptest.NewRecorder()
+	r.Handler(Readiness).ServeHTTP(resp, httptest.NewRequest("GET", "/readyz?verbose", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, resp.Code)
+	assert.Equal(t, "application/json", resp.Header().Get("Content-Type"))
+
+	var report Report
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &report))
+	assert.Equal(t, StatusFailed, report.Status)
+	assert.Equal(t, map[string]string{"a": StatusOK, "b": StatusFailed}, statuses(report))
+	assert.Equal(t, errBroken.Error(), report.Checks[1].Error)
+}
+
+func TestListener(t *testing.T) {
+	l := NewListener("primary")
+	check := l.Check()
+	assert.Equal(t, "listener.primary", check.Name)
+	assert.ErrorIs(t, check.Check(context.Background()), errNotListening)
+
+	inner, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	wrapped := l.Middleware(inner)
+	assert.NoError(t, check.Check(context.Background()))
+
+	require.NoError(t, wrapped.Close())
+	assert.ErrorIs(t, check.Check(context.Background()), errClosed)
+}
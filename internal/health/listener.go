@@ -0,0 +1,102 @@
+// SPDX-FileCopyrightText: 2024 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package health
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+)
+
+var (
+	errNotListening = errors.New("not listening")
+	errClosed       = errors.New("listener closed")
+)
+
+// Listener tracks a server's net.Listener so a check can report if the server
+// is accepting connections.
+type Listener struct {
+	name string
+
+	m      sync.Mutex
+	addr   net.Addr
+	closed bool
+	err    error
+}
+
+// NewListener creates a Listener for the named server.
+func NewListener(name string) *Listener {
+	return &Listener{
+		name: name,
+	}
+}
+
+// Middleware wraps the server's listener.  It fits the arrangehttp listener
+// middleware.
+func (l *Listener) Middleware(next net.Listener) net.Listener {
+	l.m.Lock()
+	defer l.m.Unlock()
+
+	l.addr = next.Addr()
+	l.closed = false
+	l.err = nil
+
+	return &trackedListener{
+		Listener: next,
+		tracker:  l,
+	}
+}
+
+// Check is a critical readiness and startup check that fails until the
+// listener is bound and after it is closed or stops accepting connections.
+func (l *Listener) Check() Check {
+	return Check{
+		Name:     "listener." + l.name,
+		Probes:   Readiness | Startup,
+		Critical: true,
+		Check: func(context.Context) error {
+			l.m.Lock()
+			defer l.m.Unlock()
+
+			switch {
+			case l.addr == nil:
+				return errNotListening
+			case l.err != nil:
+				return fmt.Errorf("%s: %w", l.addr, l.err)
+			case l.closed:
+				return fmt.Errorf("%s: %w", l.addr, errClosed)
+			}
+			return nil
+		},
+	}
+}
+
+type trackedListener struct {
+	net.Listener
+	tracker *Listener
+}
+
+func (tl *trackedListener) Accept() (net.Conn, error) {
+	conn, err := tl.Listener.Accept()
+	if err != nil && !errors.Is(err, net.ErrClosed) {
+		var ne net.Error
+		if !errors.As(err, &ne) || !ne.Timeout() {
+			tl.tracker.m.Lock()
+			tl.tracker.err = err
+			tl.tracker.m.Unlock()
+		}
+	}
+
+	return conn, err
+}
+
+func (tl *trackedListener) Close() error {
+	tl.tracker.m.Lock()
+	tl.tracker.closed = true
+	tl.tracker.m.Unlock()
+
+	return tl.Listener.Close()
+}
@@ -0,0 +1,41 @@
+// SPDX-FileCopyrightText: 2024 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package health
+
+import "fmt"
+
+type Option interface {
+	apply(*Registry) error
+}
+
+type optionFunc func(*Registry) error
+
+func (f optionFunc) apply(r *Registry) error {
+	return f(r)
+}
+
+func WithConfig(c Config) Option {
+	return optionFunc(func(r *Registry) error {
+		r.config = c
+		return nil
+	})
+}
+
+// WithChecks adds checks to the registry.  Each name can only be used once.
+func WithChecks(checks ...Check) Option {
+	return optionFunc(func(r *Registry) error {
+		for _, c := range checks {
+			if c.Name == "" || c.Check == nil {
+				return fmt.Errorf("%w: check '%s' is empty", ErrInvalidCheck, c.Name)
+			}
+			for _, existing := range r.checks {
+				if existing.Name == c.Name {
+					return fmt.Errorf("%w: check '%s' is already registered", ErrInvalidCheck, c.Name)
+				}
+			}
+			r.checks = append(r.checks, &check{Check: c})
+		}
+		return nil
+	})
+}
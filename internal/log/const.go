@@ -9,6 +9,21 @@ const (
 	ErrorStackTrace = "error.stack_trace"
 	ErrorMessage    = "error.message"
 	RequestBody     = "http.request.body.content"
+	TraceID         = "trace.id"
+	SpanID          = "span.id"
+
+	// access log fields
+	ClientAddress          = "client.address"
+	EventDuration          = "event.duration"
+	HTTPRequestID          = "http.request.id"
+	HTTPRequestMethod      = "http.request.method"
+	HTTPResponseBodyBytes  = "http.response.body.bytes"
+	HTTPResponseStatusCode = "http.response.status_code"
+	HTTPRoute              = "http.route"
+	ServerName             = "server.name"
+	URLPath                = "url.path"
+	UserAgent              = "user_agent.original"
+	UserName               = "user.name"
 
 	// fides specific fields
 	// TBD
@@ -44,13 +44,63 @@ var fxMetrics = []metricDefinition{
 		Labels:  "outcome, partnerid, status_code",
 		Buckets: "10, 100, 1000, 5000, 10000, 100000, 500000, 1000000, 2000000",
 	},
+
+	{
+		Type: GAUGE,
+		Name: "oking_event_queue_depth",
+		Help: "The number of oking events waiting to be delivered to listeners.",
+	},
+
+	{
+		Type: COUNTER,
+		Name: "oking_event_dropped_count",
+		Help: "The number of oking events dropped because the queue was full.",
+	},
+
+	{
+		Type:   COUNTER,
+		Name:   "oking_sink_dropped_count",
+		Help:   "The number of oking events a sink dropped because its queue was full.",
+		Labels: "sink",
+	},
+
+	{
+		Type:   COUNTER,
+		Name:   "server_handler_error_count",
+		Help:   "The number of requests a handler failed without a response, such as by panicking.",
+		Labels: "server, route, error",
+	},
+
+	{
+		Type: COUNTER,
+		Name: "auth_key_reload_failure_count",
+		Help: "The number of times reloading the token decryption keys failed.",
+	},
+
+	{
+		Type:   COUNTER,
+		Name:   "outbound_token_fetch_count",
+		Help:   "The number of times an outbound token fetch has been attempted.",
+		Labels: "outcome, status_code",
+	},
+
+	{
+		Type:    HISTOGRAM,
+		Name:    "outbound_token_fetch_duration",
+		Help:    "The duration of the outbound token fetch.",
+		Labels:  "outcome, status_code",
+		Buckets: "10, 100, 1000, 5000, 10000, 100000, 500000, 1000000, 2000000",
+	},
 }
 
 func Provide() fx.Option {
 	var opts []fx.Option // nolint: prealloc
 
 	for _, m := range fxMetrics {
-		labels := strings.Split(m.Labels, ",")
+		var labels []string
+		if strings.TrimSpace(m.Labels) != "" {
+			labels = strings.Split(m.Labels, ",")
+		}
 		for i := range labels {
 			labels[i] = strings.TrimSpace(labels[i])
 		}
@@ -0,0 +1,111 @@
+// SPDX-FileCopyrightText: 2024 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+// Package middleware has the pieces the http middleware share: a response
+// writer that records the response and the lookup of the route that matched.
+package middleware
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// Recorder keeps the status code and size of the response.
+//
+// The writers the middleware wrap are decorated by the touchhttp metrics,
+// which only keep http.Flusher and http.Hijacker when the writer they wrap
+// implements them directly.  Recorder implements both, passing them on with
+// an http.ResponseController, so streaming and hijacking keep working however
+// the middleware are stacked.
+type Recorder struct {
+	http.ResponseWriter
+
+	// Code is the status code of the response.  It is 200 until WriteHeader
+	// is called.
+	Code int
+
+	// Bytes is the size of the response body written so far.
+	Bytes int64
+
+	// Started is set once the response has started, after which it can't be
+	// replaced.
+	Started bool
+}
+
+// NewRecorder creates the recorder of the writer.
+func NewRecorder(resp http.ResponseWriter) *Recorder {
+	return &Recorder{
+		ResponseWriter: resp,
+		Code:           http.StatusOK,
+	}
+}
+
+func (r *Recorder) WriteHeader(code int) {
+	// Informational responses don't start the response.
+	if !r.Started && code >= http.StatusOK {
+		r.Code = code
+		r.Started = true
+	}
+	r.ResponseWriter.WriteHeader(code)
+}
+
+func (r *Recorder) Write(b []byte) (int, error) {
+	r.Started = true
+	n, err := r.ResponseWriter.Write(b)
+	r.Bytes += int64(n)
+	return n, err
+}
+
+// Flush implements http.Flusher.
+func (r *Recorder) Flush() {
+	r.Started = true
+	_ = http.NewResponseController(r.ResponseWriter).Flush()
+}
+
+// Hijack implements http.Hijacker.  It fails with http.ErrNotSupported if
+// the underlying writer can't be hijacked.
+func (r *Recorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, rw, err := http.NewResponseController(r.ResponseWriter).Hijack()
+	if err == nil {
+		r.Started = true
+	}
+	return conn, rw, err
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (r *Recorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+// Route finds the pattern of the route that served a request.
+type Route struct {
+	rctx *chi.Context
+	req  *http.Request
+}
+
+// TrackRoute prepares the request so the pattern of the route that matched
+// can be read once it has been served.  chi uses the route context it finds,
+// so one is added if there is none, and http.ServeMux records the pattern on
+// the request.  The returned request has to be the one that is served.
+func TrackRoute(req *http.Request) (*http.Request, *Route) {
+	rctx := chi.RouteContext(req.Context())
+	if rctx == nil {
+		rctx = chi.NewRouteContext()
+		req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+	}
+
+	return req, &Route{rctx: rctx, req: req}
+}
+
+// Pattern returns the pattern of the route, or an empty string if no route
+// matched.
+func (r *Route) Pattern() string {
+	if pattern := r.rctx.RoutePattern(); pattern != "" {
+		return pattern
+	}
+	return r.req.Pattern
+}
@@ -0,0 +1,124 @@
+// SPDX-FileCopyrightText: 2024 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package middleware
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/xmidt-org/httpaux/observe"
+)
+
+func TestRecorder(t *testing.T) {
+	resp := httptest.NewRecorder()
+	rec := NewRecorder(resp)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.False(t, rec.Started)
+
+	rec.WriteHeader(http.StatusTeapot)
+	rec.WriteHeader(http.StatusInternalServerError)
+	_, _ = io.WriteString(rec, "hello")
+
+	assert.True(t, rec.Started)
+	assert.Equal(t, http.StatusTeapot, rec.Code)
+	assert.Equal(t, int64(5), rec.Bytes)
+	assert.Equal(t, resp, rec.Unwrap())
+
+	// Informational responses don't start the response.
+	rec = NewRecorder(httptest.NewRecorder())
+	rec.WriteHeader(http.StatusContinue)
+	assert.False(t, rec.Started)
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestRecorderFlush(t *testing.T) {
+	resp := httptest.NewRecorder()
+	rec := NewRecorder(resp)
+
+	// The touchhttp metrics wrap the writers with observe.
+	w := observe.New(rec)
+	f, ok := w.(http.Flusher)
+	if assert.True(t, ok) {
+		f.Flush()
+	}
+	assert.True(t, resp.Flushed)
+	assert.True(t, rec.Started)
+}
+
+func TestRecorderHijack(t *testing.T) {
+	var err error
+	server := httptest.NewServer(http.HandlerFunc(func(resp http.ResponseWriter, _ *http.Request) {
+		w := observe.New(NewRecorder(resp))
+		if _, ok := w.(http.Hijacker); !ok {
+			resp.WriteHeader(http.StatusNotImplemented)
+			return
+		}
+
+		conn, _, herr := http.NewResponseController(w).Hijack()
+		err = herr
+		if herr == nil {
+			_, _ = io.WriteString(conn, "HTTP/1.1 204 No Content\r\nConnection: close\r\n\r\n")
+			conn.Close()
+		}
+	}))
+	defer server.Close()
+
+	resp, rerr := http.Get(server.URL)
+	if assert.NoError(t, rerr) {
+		resp.Body.Close()
+		assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+	}
+	assert.NoError(t, err)
+
+	// httptest.ResponseRecorder can't be hijacked.
+	_, _, err = NewRecorder(httptest.NewRecorder()).Hijack()
+	assert.ErrorIs(t, err, http.ErrNotSupported)
+}
+
+func TestTrackRoute(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler func() http.Handler
+		path    string
+		want    string
+	}{
+		{
+			name: "chi",
+			handler: func() http.Handler {
+				mux := chi.NewMux()
+				mux.Get("/api/{version}/ok", func(http.ResponseWriter, *http.Request) {})
+				return mux
+			},
+			path: "/api/v1/ok",
+			want: "/api/{version}/ok",
+		}, {
+			name: "serve mux",
+			handler: func() http.Handler {
+				mux := http.NewServeMux()
+				mux.HandleFunc("GET /debug/{name}", func(http.ResponseWriter, *http.Request) {})
+				return mux
+			},
+			path: "/debug/heap",
+			want: "GET /debug/{name}",
+		}, {
+			name: "no match",
+			handler: func() http.Handler {
+				return chi.NewMux()
+			},
+			path: "/missing",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			req, route := TrackRoute(httptest.NewRequest("GET", tc.path, nil))
+			tc.handler().ServeHTTP(httptest.NewRecorder(), req)
+			assert.Equal(t, tc.want, route.Pattern())
+		})
+	}
+}
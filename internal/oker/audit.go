@@ -0,0 +1,466 @@
+// SPDX-FileCopyrightText: 2024 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package oker
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	kit "github.com/go-kit/kit/metrics"
+	"github.com/xmidt-org/skeleton/internal/endpoint"
+	"go.uber.org/zap"
+)
+
+const (
+	// SyncNone leaves flushing the audit log to disk to the operating system.
+	SyncNone = "none"
+
+	// SyncInterval flushes the audit log to disk every SyncInterval.
+	SyncInterval = "interval"
+
+	// SyncAlways flushes the audit log to disk after every event.
+	SyncAlways = "always"
+)
+
+const (
+	defaultAuditMaxSize      = 100
+	defaultAuditQueueSize    = 1000
+	defaultAuditSyncInterval = time.Second
+
+	auditTimeFormat = "20060102T150405.000"
+	megabyte        = 1024 * 1024
+)
+
+var errInvalidAudit = errors.New("invalid audit config")
+
+// Audit configures an append-only file of events, one JSON object per line.
+type Audit struct {
+	// File is the path of the audit log.  If this is empty, no audit log is
+	// written.
+	File string
+
+	// MaxSize is the size in megabytes the file can reach before it is
+	// rotated.  Defaults to 100.
+	MaxSize int
+
+	// RotateEvery rotates the file after it has been open this long, even if
+	// it is not full.  Zero only rotates on size.
+	RotateEvery time.Duration
+
+	// MaxBackups is the number of rotated files kept.  Zero keeps them all.
+	MaxBackups int
+
+	// MaxAge is how long rotated files are kept.  Zero keeps them regardless
+	// of age.  Old files are removed at startup and after every rotation.
+	MaxAge time.Duration
+
+	// Compress gzips the rotated files.
+	Compress bool
+
+	// Sync is when the file is flushed to disk, either "none" (the default),
+	// "interval" or "always".
+	Sync string
+
+	// SyncInterval is how often the file is flushed when Sync is "interval".
+	// Defaults to 1s.
+	SyncInterval time.Duration
+
+	// QueueSize is the number of events that can wait to be written.
+	// Defaults to 1000.
+	QueueSize int
+
+	// Overflow is what happens when the queue is full.  Either "drop" (the
+	// default) or "block", which holds up the event delivery until there is
+	// room.  Dropped events are counted and logged.  "block" is only allowed
+	// when the events are dispatched asynchronously, so a full queue never
+	// holds up a request.
+	Overflow string
+}
+
+// auditLog is an OkEventListener that writes events to a rotating file.
+type auditLog struct {
+	config Audit
+	logger *zap.Logger
+	drops  *drops
+
+	file     *os.File
+	size     int64
+	openedAt time.Time
+
+	events chan OkEvent
+	stop   chan struct{}
+	done   chan struct{}
+
+	// mill compresses and removes rotated files in the background.
+	mill     chan struct{}
+	millDone chan struct{}
+}
+
+func newAuditLog(cfg Audit, logger *zap.Logger, dropped kit.Counter) (*auditLog, error) {
+	if cfg.File == "" {
+		return nil, fmt.Errorf("%w: a file is required", errInvalidAudit)
+	}
+	if cfg.MaxSize < 0 || cfg.MaxBackups < 0 || cfg.MaxAge < 0 || cfg.RotateEvery < 0 {
+		return nil, fmt.Errorf("%w: limits must not be negative", errInvalidAudit)
+	}
+
+	switch cfg.Overflow {
+	case "":
+		cfg.Overflow = endpoint.OverflowDrop
+	case endpoint.OverflowBlock, endpoint.OverflowDrop:
+	default:
+		return nil, fmt.Errorf("%w: unknown overflow policy '%s'", errInvalidAudit, cfg.Overflow)
+	}
+
+	switch cfg.Sync {
+	case "":
+		cfg.Sync = SyncNone
+	case SyncNone, SyncInterval, SyncAlways:
+	default:
+		return nil, fmt.Errorf("%w: unknown sync policy '%s'", errInvalidAudit, cfg.Sync)
+	}
+
+	if cfg.MaxSize == 0 {
+		cfg.MaxSize = defaultAuditMaxSize
+	}
+	if cfg.SyncInterval <= 0 {
+		cfg.SyncInterval = defaultAuditSyncInterval
+	}
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = defaultAuditQueueSize
+	}
+
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+
+	logger = logger.With(zap.String("audit", cfg.File))
+	a := auditLog{
+		config:   cfg,
+		logger:   logger,
+		drops:    newDrops("audit", dropped, logger, "audit events dropped"),
+		events:   make(chan OkEvent, cfg.QueueSize),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+		mill:     make(chan struct{}, 1),
+		millDone: make(chan struct{}),
+	}
+
+	if err := a.open(); err != nil {
+		return nil, err
+	}
+
+	// The rotated files left from before are cleaned up right away.
+	a.mill <- struct{}{}
+
+	go a.run()
+	go a.runMill()
+
+	return &a, nil
+}
+
+// OnOkEvent queues the event.  When the queue is full it waits for room, or
+// drops the event with the "drop" overflow policy.  Events that arrive after
+// the audit log is stopped are dropped.
+func (a *auditLog) OnOkEvent(e OkEvent) {
+	select {
+	case <-a.stop:
+		a.drops.add()
+		return
+	default:
+	}
+
+	if a.config.Overflow == endpoint.OverflowBlock {
+		select {
+		case a.events <- e:
+		case <-a.stop:
+			a.drops.add()
+		}
+		return
+	}
+
+	select {
+	case a.events <- e:
+	default:
+		a.drops.add()
+	}
+}
+
+// Stop writes any queued events, flushes and closes the file.  If the context
+// ends first, the remaining events are abandoned.
+func (a *auditLog) Stop(ctx context.Context) error {
+	select {
+	case <-a.stop:
+	default:
+		close(a.stop)
+	}
+
+	select {
+	case <-a.done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	select {
+	case <-a.millDone:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (a *auditLog) open() error {
+	if err := os.MkdirAll(filepath.Dir(a.config.File), 0o755); err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(a.config.File, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return err
+	}
+
+	a.file = f
+	a.size = info.Size()
+	a.openedAt = time.Now()
+
+	return nil
+}
+
+func (a *auditLog) run() {
+	defer close(a.done)
+	defer close(a.mill)
+	defer a.close()
+
+	var sync, rotate <-chan time.Time
+	if a.config.Sync == SyncInterval {
+		t := time.NewTicker(a.config.SyncInterval)
+		defer t.Stop()
+		sync = t.C
+	}
+	if a.config.RotateEvery > 0 {
+		t := time.NewTicker(min(a.config.RotateEvery, time.Second))
+		defer t.Stop()
+		rotate = t.C
+	}
+
+	for {
+		select {
+		case e := <-a.events:
+			a.write(e)
+
+		case <-sync:
+			a.sync()
+
+		case <-rotate:
+			if a.size > 0 && time.Since(a.openedAt) >= a.config.RotateEvery {
+				a.rotate()
+			}
+
+		case <-a.stop:
+			for {
+				select {
+				case e := <-a.events:
+					a.write(e)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+func (a *auditLog) write(e OkEvent) {
+	line, err := json.Marshal(e)
+	if err != nil {
+		a.logger.Error("unable to marshal audit event", zap.Error(err))
+		return
+	}
+	line = append(line, '\n')
+
+	if a.size > 0 && a.size+int64(len(line)) > int64(a.config.MaxSize)*megabyte {
+		a.rotate()
+	}
+
+	if a.file == nil {
+		a.logger.Error("audit file is not open, dropping event")
+		return
+	}
+
+	n, err := a.file.Write(line)
+	a.size += int64(n)
+	if err != nil {
+		a.logger.Error("unable to write audit event", zap.Error(err))
+		return
+	}
+
+	if a.config.Sync == SyncAlways {
+		a.sync()
+	}
+}
+
+func (a *auditLog) sync() {
+	if a.file == nil {
+		return
+	}
+
+	if err := a.file.Sync(); err != nil {
+		a.logger.Error("unable to sync audit file", zap.Error(err))
+	}
+}
+
+func (a *auditLog) close() {
+	if a.file == nil {
+		return
+	}
+
+	a.sync()
+	if err := a.file.Close(); err != nil {
+		a.logger.Error("unable to close audit file", zap.Error(err))
+	}
+	a.file = nil
+}
+
+// rotate moves the current file aside and starts a new one.
+func (a *auditLog) rotate() {
+	a.close()
+
+	backup := a.backupName(time.Now())
+	if err := os.Rename(a.config.File, backup); err != nil {
+		a.logger.Error("unable to rotate audit file", zap.Error(err))
+	}
+
+	if err := a.open(); err != nil {
+		a.logger.Error("unable to open audit file", zap.Error(err))
+	}
+
+	select {
+	case a.mill <- struct{}{}:
+	default:
+	}
+}
+
+// backupName returns an unused name for a rotated file.
+func (a *auditLog) backupName(at time.Time) string {
+	ext := filepath.Ext(a.config.File)
+	base := strings.TrimSuffix(a.config.File, ext)
+
+	for {
+		name := fmt.Sprintf("%s-%s%s", base, at.UTC().Format(auditTimeFormat), ext)
+		if _, err := os.Stat(name); errors.Is(err, os.ErrNotExist) {
+			if _, err := os.Stat(name + ".gz"); errors.Is(err, os.ErrNotExist) {
+				return name
+			}
+		}
+		at = at.Add(time.Millisecond)
+	}
+}
+
+func (a *auditLog) runMill() {
+	defer close(a.millDone)
+
+	for range a.mill {
+		if err := a.millOnce(); err != nil {
+			a.logger.Error("unable to clean up rotated audit files", zap.Error(err))
+		}
+	}
+}
+
+// backups returns the rotated files, newest first.
+func (a *auditLog) backups() ([]string, error) {
+	ext := filepath.Ext(a.config.File)
+	prefix := filepath.Base(strings.TrimSuffix(a.config.File, ext)) + "-"
+	dir := filepath.Dir(a.config.File)
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var list []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, prefix) {
+			continue
+		}
+
+		stamp := strings.TrimSuffix(strings.TrimSuffix(name[len(prefix):], ".gz"), ext)
+		if _, err := time.Parse(auditTimeFormat, stamp); err != nil {
+			continue
+		}
+		list = append(list, filepath.Join(dir, name))
+	}
+
+	// The timestamps sort in time order.
+	slices.Sort(list)
+	slices.Reverse(list)
+
+	return list, nil
+}
+
+// millOnce removes the rotated files that are past the retention limits and
+// compresses the rest.
+func (a *auditLog) millOnce() error {
+	list, err := a.backups()
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for i, name := range list {
+		expired := a.config.MaxBackups > 0 && i >= a.config.MaxBackups
+		if !expired && a.config.MaxAge > 0 {
+			if info, err := os.Stat(name); err == nil {
+				expired = time.Since(info.ModTime()) > a.config.MaxAge
+			}
+		}
+
+		switch {
+		case expired:
+			errs = append(errs, os.Remove(name))
+		case a.config.Compress && !strings.HasSuffix(name, ".gz"):
+			errs = append(errs, compress(name))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// compress gzips the file and removes the original.
+func compress(name string) error {
+	src, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := os.OpenFile(name+".gz", os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+
+	gz := gzip.NewWriter(dst)
+	_, err = io.Copy(gz, src)
+	err = errors.Join(err, gz.Close(), dst.Sync(), dst.Close())
+	if err != nil {
+		_ = os.Remove(name + ".gz")
+		return err
+	}
+
+	return os.Remove(name)
+}
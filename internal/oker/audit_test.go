@@ -0,0 +1,279 @@
+// SPDX-FileCopyrightText: 2024 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package oker
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	kit "github.com/go-kit/kit/metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xmidt-org/skeleton/internal/endpoint"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// counter counts by the label values.
+type counter struct {
+	labels []string
+	values map[string]float64
+}
+
+func newCounter() *counter {
+	return &counter{values: make(map[string]float64)}
+}
+
+func (c *counter) With(lvs ...string) kit.Counter {
+	return &counter{
+		labels: append(append([]string{}, c.labels...), lvs...),
+		values: c.values,
+	}
+}
+
+func (c *counter) Add(v float64) {
+	c.values[strings.Join(c.labels, ",")] += v
+}
+
+// readLines returns the events in the file, which may be gzipped.
+func readLines(t *testing.T, name string) []map[string]any {
+	f, err := os.Open(name)
+	require.NoError(t, err)
+	defer f.Close()
+
+	var r io.Reader = f
+	if strings.HasSuffix(name, ".gz") {
+		gz, err := gzip.NewReader(f)
+		require.NoError(t, err)
+		r = gz
+	}
+
+	var lines []map[string]any
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		var e map[string]any
+		require.NoError(t, json.Unmarshal(sc.Bytes(), &e))
+		lines = append(lines, e)
+	}
+	require.NoError(t, sc.Err())
+	return lines
+}
+
+func TestAuditLog(t *testing.T) {
+	for _, sync := range []string{"", SyncNone, SyncInterval, SyncAlways} {
+		t.Run(sync, func(t *testing.T) {
+			name := filepath.Join(t.TempDir(), "logs", "audit.jsonl")
+
+			a, err := newAuditLog(Audit{File: name, Sync: sync, SyncInterval: time.Millisecond}, nil, nil)
+			require.NoError(t, err)
+
+			a.OnOkEvent(OkEvent{PartnerID: "comcast", StatusCode: http.StatusOK})
+			a.OnOkEvent(OkEvent{PartnerID: "sky", StatusCode: http.StatusOK})
+			require.NoError(t, a.Stop(context.Background()))
+
+			lines := readLines(t, name)
+			require.Len(t, lines, 2)
+			assert.Equal(t, "comcast", lines[0]["partner_id"])
+			assert.Equal(t, "sky", lines[1]["partner_id"])
+
+			// Reopening appends to the existing file.
+			a, err = newAuditLog(Audit{File: name}, nil, nil)
+			require.NoError(t, err)
+			a.OnOkEvent(OkEvent{PartnerID: "other"})
+			require.NoError(t, a.Stop(context.Background()))
+			assert.Len(t, readLines(t, name), 3)
+		})
+	}
+}
+
+func TestAuditRotateSize(t *testing.T) {
+	dir := t.TempDir()
+	name := filepath.Join(dir, "audit.jsonl")
+
+	a, err := newAuditLog(Audit{File: name, MaxSize: 1, QueueSize: 20000}, nil, nil)
+	require.NoError(t, err)
+
+	// Each line is around 100 bytes, so this is more than 1MB.
+	const count = 15000
+	for range count {
+		a.OnOkEvent(OkEvent{PartnerID: "comcast", RequestID: strings.Repeat("x", 32)})
+	}
+	require.NoError(t, a.Stop(context.Background()))
+
+	backups, err := a.backups()
+	require.NoError(t, err)
+	require.NotEmpty(t, backups)
+
+	total := len(readLines(t, name))
+	for _, b := range backups {
+		info, err := os.Stat(b)
+		require.NoError(t, err)
+		assert.LessOrEqual(t, info.Size(), int64(megabyte))
+		total += len(readLines(t, b))
+	}
+	assert.Equal(t, count, total)
+}
+
+func TestAuditRotateTime(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "audit.jsonl")
+
+	a, err := newAuditLog(Audit{File: name, RotateEvery: 5 * time.Millisecond}, nil, nil)
+	require.NoError(t, err)
+	defer a.Stop(context.Background())
+
+	a.OnOkEvent(OkEvent{PartnerID: "comcast"})
+
+	require.Eventually(t, func() bool {
+		backups, err := a.backups()
+		return err == nil && len(backups) == 1
+	}, time.Second, time.Millisecond)
+
+	// An empty file is not rotated.
+	time.Sleep(20 * time.Millisecond)
+	backups, err := a.backups()
+	require.NoError(t, err)
+	assert.Len(t, backups, 1)
+}
+
+func TestAuditRetention(t *testing.T) {
+	dir := t.TempDir()
+	name := filepath.Join(dir, "audit.jsonl")
+
+	// Old rotated files and files that are not backups.
+	old := time.Now().Add(-time.Hour)
+	newest := ""
+	for i := range 4 {
+		newest = filepath.Join(dir, "audit-"+old.Add(time.Duration(i)*time.Minute).UTC().Format(auditTimeFormat)+".jsonl")
+		require.NoError(t, os.WriteFile(newest, []byte("{}\n"), 0o600))
+	}
+	other := filepath.Join(dir, "audit-notes.jsonl")
+	require.NoError(t, os.WriteFile(other, nil, 0o600))
+
+	a, err := newAuditLog(Audit{
+		File:        name,
+		RotateEvery: 5 * time.Millisecond,
+		MaxBackups:  2,
+		Compress:    true,
+	}, nil, nil)
+	require.NoError(t, err)
+
+	a.OnOkEvent(OkEvent{PartnerID: "comcast"})
+
+	require.Eventually(t, func() bool {
+		// The old files are cleaned up at startup, so wait for the rotation.
+		backups, err := a.backups()
+		if err != nil || len(backups) != 2 || backups[0] <= newest+".gz" {
+			return false
+		}
+		for _, b := range backups {
+			if !strings.HasSuffix(b, ".gz") {
+				return false
+			}
+		}
+		return true
+	}, time.Second, time.Millisecond)
+	require.NoError(t, a.Stop(context.Background()))
+
+	// The newest backup holds the event.
+	backups, err := a.backups()
+	require.NoError(t, err)
+	lines := readLines(t, backups[0])
+	require.Len(t, lines, 1)
+	assert.Equal(t, "comcast", lines[0]["partner_id"])
+
+	assert.FileExists(t, other)
+}
+
+func TestAuditMaxAge(t *testing.T) {
+	dir := t.TempDir()
+	name := filepath.Join(dir, "audit.jsonl")
+
+	old := filepath.Join(dir, "audit-"+time.Now().UTC().Format(auditTimeFormat)+".jsonl")
+	require.NoError(t, os.WriteFile(old, []byte("{}\n"), 0o600))
+	require.NoError(t, os.Chtimes(old, time.Now(), time.Now().Add(-48*time.Hour)))
+
+	// The old file is removed at startup, without waiting for a rotation.
+	a, err := newAuditLog(Audit{File: name, MaxAge: 24 * time.Hour}, nil, nil)
+	require.NoError(t, err)
+	defer a.Stop(context.Background())
+
+	require.Eventually(t, func() bool {
+		_, err := os.Stat(old)
+		return os.IsNotExist(err)
+	}, time.Second, time.Millisecond)
+}
+
+func TestAuditOverflow(t *testing.T) {
+	core, logs := observer.New(zap.WarnLevel)
+	dropped := newCounter()
+
+	// Without the writer running the queue fills up.
+	a := auditLog{
+		config: Audit{Overflow: endpoint.OverflowDrop},
+		events: make(chan OkEvent, 1),
+		stop:   make(chan struct{}),
+		drops:  newDrops("audit", dropped, zap.New(core), "audit events dropped"),
+	}
+	for range 5 {
+		a.OnOkEvent(OkEvent{PartnerID: "comcast"})
+	}
+
+	assert.Len(t, a.events, 1)
+	assert.Equal(t, map[string]float64{"sink,audit": 4}, dropped.values)
+
+	// The drops are logged once per interval.
+	entries := logs.FilterMessage("audit events dropped").All()
+	require.Len(t, entries, 1)
+	assert.Equal(t, int64(1), entries[0].ContextMap()["dropped"])
+}
+
+func TestAuditBlockAfterStop(t *testing.T) {
+	dropped := newCounter()
+
+	a, err := newAuditLog(Audit{
+		File:      filepath.Join(t.TempDir(), "audit.jsonl"),
+		QueueSize: 1,
+		Overflow:  endpoint.OverflowBlock,
+	}, nil, dropped)
+	require.NoError(t, err)
+	require.NoError(t, a.Stop(context.Background()))
+
+	// Events after the stop don't wait.
+	a.OnOkEvent(OkEvent{})
+	a.OnOkEvent(OkEvent{})
+	assert.Equal(t, map[string]float64{"sink,audit": 2}, dropped.values)
+}
+
+func TestAuditDefaultOverflow(t *testing.T) {
+	a, err := newAuditLog(Audit{File: filepath.Join(t.TempDir(), "audit.jsonl")}, nil, nil)
+	require.NoError(t, err)
+	defer a.Stop(context.Background())
+
+	assert.Equal(t, endpoint.OverflowDrop, a.config.Overflow)
+}
+
+func TestAuditInvalid(t *testing.T) {
+	dir := t.TempDir()
+	tests := []Audit{
+		{},
+		{File: filepath.Join(dir, "a.jsonl"), Sync: "sometimes"},
+		{File: filepath.Join(dir, "a.jsonl"), MaxSize: -1},
+		{File: filepath.Join(dir, "a.jsonl"), MaxAge: -1},
+		{File: filepath.Join(dir, "a.jsonl"), Overflow: "sometimes"},
+	}
+
+	for _, tc := range tests {
+		_, err := newAuditLog(tc, nil, nil)
+		assert.ErrorIs(t, err, errInvalidAudit)
+	}
+}
@@ -0,0 +1,59 @@
+// SPDX-FileCopyrightText: 2024 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package oker
+
+import (
+	"sync"
+	"time"
+
+	kit "github.com/go-kit/kit/metrics"
+	"github.com/go-kit/kit/metrics/discard"
+	"go.uber.org/zap"
+)
+
+// dropLogInterval is the least time between two logs of dropped events.
+const dropLogInterval = 10 * time.Second
+
+// drops counts the events a sink drops.  Every drop is counted, but the log
+// is written at most once every dropLogInterval with the number of events
+// dropped since, so a full queue doesn't flood the log.
+type drops struct {
+	counter kit.Counter
+	logger  *zap.Logger
+	message string
+
+	m      sync.Mutex
+	count  int
+	logged time.Time
+}
+
+func newDrops(sink string, counter kit.Counter, logger *zap.Logger, message string) *drops {
+	if counter == nil {
+		counter = discard.NewCounter()
+	}
+
+	return &drops{
+		counter: counter.With("sink", sink),
+		logger:  logger,
+		message: message,
+	}
+}
+
+func (d *drops) add() {
+	d.counter.Add(1)
+
+	d.m.Lock()
+	d.count++
+	now := time.Now()
+	if now.Sub(d.logged) < dropLogInterval {
+		d.m.Unlock()
+		return
+	}
+	count := d.count
+	d.count = 0
+	d.logged = now
+	d.m.Unlock()
+
+	d.logger.Warn(d.message, zap.Int("dropped", count))
+}
@@ -4,9 +4,12 @@
 package oker
 
 import (
-	"fmt"
-	"strings"
+	"encoding/json"
+	"strconv"
 	"time"
+
+	"github.com/xmidt-org/skeleton/internal/endpoint"
+	"go.uber.org/zap/zapcore"
 )
 
 // OkEvent is the event that is sent about an ok request.
@@ -17,6 +20,22 @@ type OkEvent struct {
 	// PartnerID is the partner id of the request.
 	PartnerID string
 
+	// Method is the http method of the request.
+	Method string
+
+	// Route is the route pattern that matched the request.
+	Route string
+
+	// RemoteAddr is the network address that sent the request.
+	RemoteAddr string
+
+	// RequestID is the id the requestid middleware gave the request, if any.
+	RequestID string
+
+	// TraceID and SpanID identify the span of the request, if it was traced.
+	TraceID string
+	SpanID  string
+
 	// Duration is the time needed to ok the request.
 	Duration time.Duration
 
@@ -27,21 +46,116 @@ type OkEvent struct {
 	Err error
 }
 
+// String returns the JSON form of the event.
 func (e OkEvent) String() string {
-	buf := strings.Builder{}
+	b, _ := e.MarshalJSON()
+	return string(b)
+}
+
+// outcome reports if the request succeeded.
+func (e OkEvent) outcome() string {
+	return endpoint.Outcome(e.Err)
+}
+
+// Result describes the request for the standard telemetry.
+func (e OkEvent) Result() endpoint.Result {
+	return endpoint.Result{
+		Err:      e.Err,
+		Duration: e.Duration,
+		Labels: []string{
+			"status_code", strconv.Itoa(e.StatusCode),
+			"partnerid", e.PartnerID,
+		},
+		TraceID: e.TraceID,
+		SpanID:  e.SpanID,
+	}
+}
+
+// eventJSON is the encoded form of an OkEvent shared by the logs and every
+// sink.  The keys are:
+//
+//	at           string  the request time in UTC, RFC 3339 with nanoseconds
+//	partner_id   string  omitted if empty
+//	method       string  omitted if empty
+//	route        string  omitted if empty
+//	remote_addr  string  omitted if empty
+//	request_id   string  omitted if empty
+//	trace_id     string  omitted if the request was not traced
+//	span_id      string  omitted if the request was not traced
+//	duration_ms  number  fractional milliseconds
+//	status_code  number
+//	outcome      string  "success" or "failure"
+//	error        string  the error message, omitted if there is no error
+//
+// Keys may be added, but existing keys are not renamed or removed.
+type eventJSON struct {
+	At         string  `json:"at"`
+	PartnerID  string  `json:"partner_id,omitempty"`
+	Method     string  `json:"method,omitempty"`
+	Route      string  `json:"route,omitempty"`
+	RemoteAddr string  `json:"remote_addr,omitempty"`
+	RequestID  string  `json:"request_id,omitempty"`
+	TraceID    string  `json:"trace_id,omitempty"`
+	SpanID     string  `json:"span_id,omitempty"`
+	Duration   float64 `json:"duration_ms"`
+	StatusCode int     `json:"status_code"`
+	Outcome    string  `json:"outcome"`
+	Err        string  `json:"error,omitempty"`
+}
+
+func (e OkEvent) encoded() eventJSON {
+	ej := eventJSON{
+		At:         e.At.UTC().Format(time.RFC3339Nano),
+		PartnerID:  e.PartnerID,
+		Method:     e.Method,
+		Route:      e.Route,
+		RemoteAddr: e.RemoteAddr,
+		RequestID:  e.RequestID,
+		TraceID:    e.TraceID,
+		SpanID:     e.SpanID,
+		Duration:   float64(e.Duration) / float64(time.Millisecond),
+		StatusCode: e.StatusCode,
+		Outcome:    e.outcome(),
+	}
+	if e.Err != nil {
+		ej.Err = e.Err.Error()
+	}
+	return ej
+}
 
-	buf.WriteString("oker.OkEvent{\n")
-	buf.WriteString(fmt.Sprintf("  At:         %s\n", e.At.Format(time.RFC3339)))
-	buf.WriteString(fmt.Sprintf("  Duration:   %s\n", e.Duration.String()))
-	buf.WriteString(fmt.Sprintf("  StatusCode: %d\n", e.StatusCode))
-	buf.WriteString(fmt.Sprintf("  Err:        %v\n", e.Err))
-	buf.WriteString("}")
+// MarshalJSON encodes the event using the eventJSON schema.
+func (e OkEvent) MarshalJSON() ([]byte, error) {
+	return json.Marshal(e.encoded())
+}
+
+// MarshalLogObject encodes the event using the eventJSON schema so it can be
+// logged with zap.Object or zap.Inline.  The trace ids are left out since the
+// telemetry logs them as trace.id and span.id, like every request log.
+func (e OkEvent) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	ej := e.encoded()
+
+	enc.AddString("at", ej.At)
+	addNonEmpty(enc, "partner_id", ej.PartnerID)
+	addNonEmpty(enc, "method", ej.Method)
+	addNonEmpty(enc, "route", ej.Route)
+	addNonEmpty(enc, "remote_addr", ej.RemoteAddr)
+	addNonEmpty(enc, "request_id", ej.RequestID)
+	enc.AddFloat64("duration_ms", ej.Duration)
+	enc.AddInt("status_code", ej.StatusCode)
+	enc.AddString("outcome", ej.Outcome)
+	addNonEmpty(enc, "error", ej.Err)
+
+	return nil
+}
 
-	return buf.String()
+func addNonEmpty(enc zapcore.ObjectEncoder, key, value string) {
+	if value != "" {
+		enc.AddString(key, value)
+	}
 }
 
 // OkEventListener is the interface that must be implemented by types that
-// want to receive FetchEvent notifications.
+// want to receive OkEvent notifications.
 type OkEventListener interface {
 	OnOkEvent(OkEvent)
 }
@@ -51,6 +165,6 @@ type OkEventListener interface {
 // OkEventListener interface.
 type OkEventListenerFunc func(OkEvent)
 
-func (f OkEventListenerFunc) OnEventEvent(e OkEvent) {
+func (f OkEventListenerFunc) OnOkEvent(e OkEvent) {
 	f(e)
 }
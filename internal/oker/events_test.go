@@ -0,0 +1,100 @@
+// SPDX-FileCopyrightText: 2024 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package oker
+
+import (
+	"encoding/json"
+	"errors"
+	"maps"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestOkEventEncoding(t *testing.T) {
+	at := time.Date(2024, 1, 2, 3, 4, 5, 6, time.FixedZone("EST", -5*60*60))
+
+	tests := []struct {
+		name  string
+		event OkEvent
+		want  map[string]any
+	}{
+		{
+			name: "full",
+			event: OkEvent{
+				At:         at,
+				PartnerID:  "comcast",
+				Method:     "GET",
+				Route:      "/api/ok",
+				RemoteAddr: "10.0.0.1:1234",
+				RequestID:  "abc123",
+				TraceID:    "4bf92f3577b34da6a3ce929d0e0e4736",
+				SpanID:     "00f067aa0ba902b7",
+				Duration:   1500 * time.Microsecond,
+				StatusCode: http.StatusInternalServerError,
+				Err:        errors.New("boom"),
+			},
+			want: map[string]any{
+				"at":          "2024-01-02T08:04:05.000000006Z",
+				"partner_id":  "comcast",
+				"method":      "GET",
+				"route":       "/api/ok",
+				"remote_addr": "10.0.0.1:1234",
+				"request_id":  "abc123",
+				"trace_id":    "4bf92f3577b34da6a3ce929d0e0e4736",
+				"span_id":     "00f067aa0ba902b7",
+				"duration_ms": 1.5,
+				"status_code": 500.0,
+				"outcome":     "failure",
+				"error":       "boom",
+			},
+		}, {
+			name: "empty fields omitted",
+			event: OkEvent{
+				At:         at,
+				StatusCode: http.StatusOK,
+			},
+			want: map[string]any{
+				"at":          "2024-01-02T08:04:05.000000006Z",
+				"duration_ms": 0.0,
+				"status_code": 200.0,
+				"outcome":     "success",
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			b, err := json.Marshal(tc.event)
+			require.NoError(t, err)
+
+			var got map[string]any
+			require.NoError(t, json.Unmarshal(b, &got))
+			assert.Equal(t, tc.want, got)
+			assert.JSONEq(t, string(b), tc.event.String())
+
+			// The log form uses the same keys and values, except for the
+			// trace ids which the telemetry logs itself.
+			want := maps.Clone(tc.want)
+			delete(want, "trace_id")
+			delete(want, "span_id")
+
+			enc := zapcore.NewMapObjectEncoder()
+			require.NoError(t, tc.event.MarshalLogObject(enc))
+			require.Len(t, enc.Fields, len(want))
+			for k, v := range want {
+				switch v := v.(type) {
+				case float64:
+					assert.InDelta(t, v, enc.Fields[k], 0, k)
+				default:
+					assert.Equal(t, v, enc.Fields[k], k)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,189 @@
+// SPDX-FileCopyrightText: 2024 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package oker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FaultHeader is the header a request uses to choose its faults when the
+// server allows it.  The value is a comma separated list of:
+//
+//	latency=<duration>  wait before responding, for example latency=250ms
+//	status=<code>       fail with the status code, for example status=503
+//	reset               reset the connection instead of responding
+//
+// The faults in the header replace the configured faults for the request.
+const FaultHeader = "X-Oker-Fault"
+
+var (
+	// ErrInjectedFault is the error reported in the OkEvent of a request that
+	// failed because of an injected fault.
+	ErrInjectedFault = errors.New("injected fault")
+
+	errInvalidFaults     = errors.New("invalid faults config")
+	errInvalidFaultValue = errors.New("invalid fault header")
+)
+
+// Faults configures faults that are injected into the responses so clients,
+// load balancers and retries can be tested.  The zero value injects nothing.
+type Faults struct {
+	// Latency is added before every response.
+	Latency time.Duration
+
+	// LatencyJitter is the most random latency added on top of Latency.
+	LatencyJitter time.Duration
+
+	// FailurePercent is the percent (0-100) of requests that fail with one of
+	// the StatusCodes.
+	FailurePercent float64
+
+	// StatusCodes are picked at random for failed requests.  Defaults to 500.
+	StatusCodes []int
+
+	// ResetPercent is the percent (0-100) of requests where the connection is
+	// reset instead of responding.
+	ResetPercent float64
+}
+
+func (f Faults) validate() error {
+	if f.Latency < 0 || f.LatencyJitter < 0 {
+		return fmt.Errorf("%w: latency must not be negative", errInvalidFaults)
+	}
+	if f.FailurePercent < 0 || f.FailurePercent > 100 || f.ResetPercent < 0 || f.ResetPercent > 100 {
+		return fmt.Errorf("%w: percents must be between 0 and 100", errInvalidFaults)
+	}
+	for _, code := range f.StatusCodes {
+		if !isFailureStatus(code) {
+			return fmt.Errorf("%w: status code %d is not a failure", errInvalidFaults, code)
+		}
+	}
+	return nil
+}
+
+func isFailureStatus(code int) bool {
+	return code >= 400 && code <= 599
+}
+
+// fault is what is injected into a single request.
+type fault struct {
+	latency    time.Duration
+	statusCode int
+	reset      bool
+}
+
+// pick chooses the faults for a request.
+func (f Faults) pick() fault {
+	chosen := fault{
+		latency: f.Latency,
+	}
+
+	if f.LatencyJitter > 0 {
+		chosen.latency += rand.N(f.LatencyJitter)
+	}
+
+	if f.ResetPercent > 0 && rand.Float64()*100 < f.ResetPercent {
+		chosen.reset = true
+		return chosen
+	}
+
+	if f.FailurePercent > 0 && rand.Float64()*100 < f.FailurePercent {
+		chosen.statusCode = http.StatusInternalServerError
+		if len(f.StatusCodes) > 0 {
+			chosen.statusCode = f.StatusCodes[rand.IntN(len(f.StatusCodes))]
+		}
+	}
+
+	return chosen
+}
+
+func parseFaultHeader(value string) (fault, error) {
+	var chosen fault
+
+	for _, part := range strings.Split(value, ",") {
+		name, arg, _ := strings.Cut(strings.TrimSpace(part), "=")
+
+		var err error
+		switch name {
+		case "latency":
+			chosen.latency, err = time.ParseDuration(arg)
+			if err == nil && chosen.latency < 0 {
+				err = errors.New("latency must not be negative")
+			}
+		case "status":
+			chosen.statusCode, err = strconv.Atoi(arg)
+			if err == nil && !isFailureStatus(chosen.statusCode) {
+				err = fmt.Errorf("status code %d is not a failure", chosen.statusCode)
+			}
+		case "reset":
+			chosen.reset = true
+		default:
+			err = fmt.Errorf("unknown fault '%s'", name)
+		}
+
+		if err != nil {
+			return fault{}, fmt.Errorf("%w: %w", errInvalidFaultValue, err)
+		}
+	}
+
+	return chosen, nil
+}
+
+// err describes the injected failure, or returns nil if the request does not
+// fail.
+func (f fault) err() error {
+	var what string
+	switch {
+	case f.reset:
+		what = "connection reset"
+	case f.statusCode != 0:
+		what = fmt.Sprintf("status code %d", f.statusCode)
+	default:
+		return nil
+	}
+
+	if f.latency > 0 {
+		what += fmt.Sprintf(" after %s", f.latency)
+	}
+
+	return fmt.Errorf("%w: %s", ErrInjectedFault, what)
+}
+
+// wait sleeps for the latency or until the context ends.
+func (f fault) wait(ctx context.Context) {
+	if f.latency <= 0 {
+		return
+	}
+
+	t := time.NewTimer(f.latency)
+	defer t.Stop()
+
+	select {
+	case <-t.C:
+	case <-ctx.Done():
+	}
+}
+
+// resetConn closes the connection without responding.  For TCP connections
+// the client sees a reset.  If the connection can't be taken over the request
+// is aborted instead.
+func resetConn(resp http.ResponseWriter) {
+	conn, _, err := http.NewResponseController(resp).Hijack()
+	if err != nil {
+		panic(http.ErrAbortHandler)
+	}
+
+	if tcp, ok := conn.(*net.TCPConn); ok {
+		_ = tcp.SetLinger(0)
+	}
+	_ = conn.Close()
+}
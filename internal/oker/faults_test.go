@@ -0,0 +1,183 @@
+// SPDX-FileCopyrightText: 2024 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package oker
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newFaultServer(t *testing.T, f Faults, opts ...Option) (*Server, *[]OkEvent) {
+	var events []OkEvent
+	opts = append(opts,
+		WithConfig(Config{Faults: f}),
+		AddOkEventListener(OkEventListenerFunc(func(e OkEvent) {
+			events = append(events, e)
+		})),
+	)
+
+	s, err := New(opts...)
+	require.NoError(t, err)
+
+	return s, &events
+}
+
+func TestFaults(t *testing.T) {
+	tests := []struct {
+		name   string
+		faults Faults
+		status []int
+		err    string
+		slow   time.Duration
+	}{
+		{name: "none", status: []int{http.StatusOK}},
+		{
+			name:   "failure",
+			faults: Faults{FailurePercent: 100},
+			status: []int{http.StatusInternalServerError},
+			err:    "injected fault: status code 500",
+		}, {
+			name:   "chosen status codes",
+			faults: Faults{FailurePercent: 100, StatusCodes: []int{502, 503}},
+			status: []int{502, 503},
+			err:    "injected fault: status code 50",
+		}, {
+			name:   "latency",
+			faults: Faults{Latency: 10 * time.Millisecond, LatencyJitter: time.Millisecond},
+			status: []int{http.StatusOK},
+			slow:   10 * time.Millisecond,
+		}, {
+			name:   "latency and failure",
+			faults: Faults{Latency: 10 * time.Millisecond, FailurePercent: 100},
+			status: []int{http.StatusInternalServerError},
+			err:    "injected fault: status code 500 after 10ms",
+			slow:   10 * time.Millisecond,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			s, events := newFaultServer(t, tc.faults)
+
+			resp := httptest.NewRecorder()
+			s.ServeHTTP(resp, httptest.NewRequest("GET", "/ok", nil))
+
+			assert.Contains(t, tc.status, resp.Code)
+			require.Len(t, *events, 1)
+
+			e := (*events)[0]
+			assert.Equal(t, resp.Code, e.StatusCode)
+			assert.GreaterOrEqual(t, e.Duration, tc.slow)
+			if tc.err == "" {
+				assert.NoError(t, e.Err)
+				return
+			}
+			assert.ErrorIs(t, e.Err, ErrInjectedFault)
+			assert.ErrorContains(t, e.Err, tc.err)
+		})
+	}
+}
+
+func TestFaultsReset(t *testing.T) {
+	events := make(chan OkEvent, 1)
+	s, err := New(
+		WithConfig(Config{Faults: Faults{ResetPercent: 100}}),
+		AddOkEventListener(OkEventListenerFunc(func(e OkEvent) {
+			events <- e
+		})),
+	)
+	require.NoError(t, err)
+
+	srv := httptest.NewServer(s)
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL)
+	if err == nil {
+		resp.Body.Close()
+	}
+	require.Error(t, err)
+
+	select {
+	case e := <-events:
+		assert.ErrorIs(t, e.Err, ErrInjectedFault)
+		assert.ErrorContains(t, e.Err, "connection reset")
+	case <-time.After(time.Second):
+		assert.Fail(t, "no event was sent")
+	}
+}
+
+func TestFaultHeader(t *testing.T) {
+	tests := []struct {
+		name    string
+		allowed bool
+		header  string
+		status  int
+	}{
+		{name: "not allowed", header: "status=503", status: http.StatusOK},
+		{name: "status", allowed: true, header: "status=503", status: http.StatusServiceUnavailable},
+		{name: "latency and status", allowed: true, header: "latency=1ms, status=429", status: http.StatusTooManyRequests},
+		{name: "no header", allowed: true, status: http.StatusOK},
+		{name: "unknown fault", allowed: true, header: "explode", status: http.StatusBadRequest},
+		{name: "bad status", allowed: true, header: "status=200", status: http.StatusBadRequest},
+		{name: "bad latency", allowed: true, header: "latency=soon", status: http.StatusBadRequest},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			s, events := newFaultServer(t, Faults{}, WithFaultHeader(tc.allowed))
+
+			req := httptest.NewRequest("GET", "/ok", nil)
+			if tc.header != "" {
+				req.Header.Set(FaultHeader, tc.header)
+			}
+			resp := httptest.NewRecorder()
+			s.ServeHTTP(resp, req)
+
+			assert.Equal(t, tc.status, resp.Code)
+			require.Len(t, *events, 1)
+			if tc.status == http.StatusOK {
+				assert.NoError(t, (*events)[0].Err)
+			} else {
+				assert.Error(t, (*events)[0].Err)
+			}
+		})
+	}
+}
+
+func TestFaultHeaderReset(t *testing.T) {
+	s, _ := newFaultServer(t, Faults{}, WithFaultHeader(true))
+
+	srv := httptest.NewServer(s)
+	defer srv.Close()
+
+	req, err := http.NewRequest("GET", srv.URL, nil)
+	require.NoError(t, err)
+	req.Header.Set(FaultHeader, "reset")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err == nil {
+		resp.Body.Close()
+	}
+	assert.Error(t, err)
+}
+
+func TestFaultsInvalid(t *testing.T) {
+	tests := []Faults{
+		{Latency: -1},
+		{LatencyJitter: -1},
+		{FailurePercent: 101},
+		{ResetPercent: -1},
+		{StatusCodes: []int{200}},
+	}
+
+	for _, tc := range tests {
+		_, err := New(WithConfig(Config{Faults: tc}))
+		assert.ErrorIs(t, err, errInvalidFaults)
+	}
+}
@@ -4,36 +4,128 @@
 package oker
 
 import (
+	"fmt"
+	"net/http"
+
 	kit "github.com/go-kit/kit/metrics"
+	"github.com/xmidt-org/arrange/arrangehttp"
+	"github.com/xmidt-org/skeleton/internal/endpoint"
 	"go.uber.org/fx"
 	"go.uber.org/zap"
 )
 
-type telemetryIn struct {
+// DevMode reports if the service is running in development mode.  In
+// development mode requests can choose their faults using the FaultHeader.
+type DevMode bool
+
+type sinksIn struct {
 	fx.In
 
-	Logger   *zap.Logger
-	Counter  kit.Counter   `name:"oking_request_count"`
-	Duration kit.Histogram `name:"oking_call_duration"`
+	LC      fx.Lifecycle
+	Logger  *zap.Logger
+	Config  Config
+	Dropped kit.Counter `name:"oking_sink_dropped_count"`
+
+	// ClientOptions are applied to the http clients of the webhooks, such as
+	// the outbound credentials.  They are provided through the
+	// "oker.webhooks.options" fx value group.
+	ClientOptions []arrangehttp.Option[http.Client] `group:"oker.webhooks.options"`
 }
 
+type serverIn struct {
+	fx.In
+
+	LC         fx.Lifecycle
+	Config     Config
+	BuildInfo  BuildInfo   `optional:"true"`
+	DevMode    DevMode     `optional:"true"`
+	QueueDepth kit.Gauge   `name:"oking_event_queue_depth"`
+	Dropped    kit.Counter `name:"oking_event_dropped_count"`
+
+	// Listeners are the OkEventListeners provided by any module through the
+	// "oker.listeners" fx value group.
+	Listeners []OkEventListener `group:"oker.listeners"`
+}
+
+// Module provides the oker *Server.  Other modules can receive the events by
+// providing an OkEventListener to the "oker.listeners" fx value group.  The
+// listeners are removed when the application stops, after the queued events
+// have been delivered.
 var Module = fx.Module("oker",
+	endpoint.ProvideTelemetry[OkEvent]("oking request", "oking_request_count", "oking_call_duration"),
 	fx.Provide(
-		func(in telemetryIn) *telemetry {
-			return &telemetry{
-				logger:   in.Logger,
-				counter:  in.Counter,
-				duration: in.Duration,
+		fx.Annotate(
+			func(t *endpoint.Telemetry[OkEvent]) OkEventListener {
+				return OkEventListenerFunc(t.OnEvent)
+			},
+			fx.ResultTags(`group:"oker.listeners"`),
+		),
+		fx.Annotate(
+			provideSinks,
+			fx.ResultTags(`group:"oker.listeners,flatten"`),
+		),
+		func(in serverIn) (*Server, error) {
+			opts := []Option{
+				WithConfig(in.Config),
+				WithBuildInfo(in.BuildInfo),
+				WithFaultHeader(bool(in.DevMode)),
+				WithDispatchMetrics(in.QueueDepth, in.Dropped),
+			}
+
+			cancels := make([]func(), len(in.Listeners))
+			for i, l := range in.Listeners {
+				opts = append(opts, AddOkEventListener(l, &cancels[i]))
 			}
-		}),
-	fx.Provide(
-		func(cfg Config, t *telemetry) (*Server, error) {
-			a, err := New(
-				WithConfig(cfg),
-				AddOkEventListener(t),
-			)
 
-			return a, err
+			// Hooks stop in reverse order, so the listeners are removed after
+			// the server has delivered the queued events.
+			in.LC.Append(fx.StopHook(func() {
+				for _, cancel := range cancels {
+					if cancel != nil {
+						cancel()
+					}
+				}
+			}))
+
+			a, err := New(opts...)
+			if err != nil {
+				return nil, err
+			}
+
+			in.LC.Append(fx.StopHook(a.Stop))
+
+			return a, nil
 		},
 	),
 )
+
+// provideSinks creates the webhooks and audit log in the configuration.  The
+// sinks are stopped after the server so any events still queued by the server
+// are sent.
+func provideSinks(in sinksIn) ([]OkEventListener, error) {
+	var sinks []OkEventListener
+
+	for _, cfg := range in.Config.Webhooks {
+		w, err := newWebhook(cfg, in.Logger, in.Dropped, in.ClientOptions...)
+		if err != nil {
+			return nil, err
+		}
+		in.LC.Append(fx.StopHook(w.Stop))
+		sinks = append(sinks, w)
+	}
+
+	if in.Config.Audit.File != "" {
+		if in.Config.Audit.Overflow == endpoint.OverflowBlock && in.Config.Dispatch.QueueSize == 0 {
+			return nil, fmt.Errorf("%w: the block overflow policy needs asynchronous dispatch", errInvalidAudit)
+		}
+
+		a, err := newAuditLog(in.Config.Audit, in.Logger, in.Dropped)
+		if err != nil {
+			return nil, err
+		}
+		in.LC.Append(fx.StopHook(a.Stop))
+		sinks = append(sinks, a)
+	}
+
+	return sinks, nil
+}
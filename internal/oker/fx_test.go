@@ -0,0 +1,118 @@
+// SPDX-FileCopyrightText: 2024 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package oker
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	kit "github.com/go-kit/kit/metrics"
+	"github.com/go-kit/kit/metrics/discard"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xmidt-org/arrange/arrangehttp"
+	"github.com/xmidt-org/skeleton/internal/credentials"
+	"github.com/xmidt-org/skeleton/internal/endpoint"
+	"go.uber.org/fx"
+	"go.uber.org/fx/fxtest"
+	"go.uber.org/zap"
+)
+
+func TestModuleListeners(t *testing.T) {
+	var events []OkEvent
+	var s *Server
+
+	app := fxtest.New(t,
+		fx.Supply(zap.NewNop(), Config{}),
+		fx.Provide(
+			fx.Annotated{Name: "oking_request_count", Target: func() kit.Counter { return discard.NewCounter() }},
+			fx.Annotated{Name: "oking_call_duration", Target: func() kit.Histogram { return discard.NewHistogram() }},
+			fx.Annotated{Name: "oking_event_queue_depth", Target: func() kit.Gauge { return discard.NewGauge() }},
+			fx.Annotated{Name: "oking_event_dropped_count", Target: func() kit.Counter { return discard.NewCounter() }},
+			fx.Annotated{Name: "oking_sink_dropped_count", Target: func() kit.Counter { return discard.NewCounter() }},
+			fx.Annotate(
+				func() OkEventListener {
+					return OkEventListenerFunc(func(e OkEvent) {
+						events = append(events, e)
+					})
+				},
+				fx.ResultTags(`group:"oker.listeners"`),
+			),
+		),
+		Module,
+		fx.Populate(&s),
+	)
+
+	app.RequireStart()
+	s.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/ok", nil))
+	app.RequireStop()
+
+	require.Len(t, events, 1)
+
+	// The listener is removed when the application stops.
+	s.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/ok", nil))
+	assert.Len(t, events, 1)
+}
+
+func TestProvideSinksAuditBlock(t *testing.T) {
+	lc := fxtest.NewLifecycle(t)
+	file := filepath.Join(t.TempDir(), "audit.jsonl")
+	in := sinksIn{
+		LC:     lc,
+		Logger: zap.NewNop(),
+		Config: Config{
+			Audit: Audit{File: file, Overflow: endpoint.OverflowBlock},
+		},
+	}
+
+	// Blocking would hold up the requests when the events are delivered
+	// synchronously.
+	_, err := provideSinks(in)
+	assert.ErrorIs(t, err, errInvalidAudit)
+
+	in.Config.Dispatch.QueueSize = 10
+	sinks, err := provideSinks(in)
+	require.NoError(t, err)
+	assert.Len(t, sinks, 1)
+
+	lc.RequireStart().RequireStop()
+}
+
+func TestProvideSinksCredentials(t *testing.T) {
+	tokens := httptest.NewServer(http.HandlerFunc(func(resp http.ResponseWriter, _ *http.Request) {
+		resp.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(resp, `{"access_token":"token","token_type":"Bearer","expires_in":3600}`)
+	}))
+	defer tokens.Close()
+
+	p, err := credentials.New(credentials.WithConfig(credentials.Config{
+		TokenURL:     tokens.URL,
+		ClientID:     "client",
+		ClientSecret: "secret",
+	}))
+	require.NoError(t, err)
+
+	r := newReceiver(t)
+	lc := fxtest.NewLifecycle(t)
+	sinks, err := provideSinks(sinksIn{
+		LC:            lc,
+		Logger:        zap.NewNop(),
+		Config:        Config{Webhooks: []Webhook{{URL: r.URL}}},
+		ClientOptions: []arrangehttp.Option[http.Client]{p.ClientOption()},
+	})
+	require.NoError(t, err)
+	require.Len(t, sinks, 1)
+
+	lc.RequireStart()
+	sinks[0].OnOkEvent(OkEvent{StatusCode: http.StatusOK})
+	lc.RequireStop()
+
+	r.m.Lock()
+	defer r.m.Unlock()
+	require.Len(t, r.headers, 1)
+	assert.Equal(t, "Bearer token", r.headers[0].Get("Authorization"))
+}
@@ -4,53 +4,212 @@
 package oker
 
 import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"net/http"
 	"time"
 
-	"github.com/xmidt-org/eventor"
+	"github.com/go-chi/chi/v5"
+	kit "github.com/go-kit/kit/metrics"
+	"github.com/xmidt-org/skeleton/internal/apiauth"
+	"github.com/xmidt-org/skeleton/internal/endpoint"
+	"github.com/xmidt-org/skeleton/internal/requestid"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type Config struct {
+	// Name is the name of the service reported in the response.
 	Name string
-}
 
-type Server struct {
-	config           Config
-	okEventListeners eventor.Eventor[OkEventListener]
+	// InstanceID identifies this instance of the service in the response.
+	// If this is not set, a random id is generated at startup.
+	InstanceID string
+
+	// Dispatch configures how events are delivered to the listeners.
+	Dispatch endpoint.Dispatch
+
+	// Webhooks are the URLs events are sent to.
+	Webhooks []Webhook
+
+	// Audit configures the file events are written to.
+	Audit Audit
+
+	// Recent configures the buffer of recent events.
+	Recent Recent
+
+	// Stream configures the live stream of events.
+	Stream Stream
+
+	// Faults configures the faults injected into the responses.
+	Faults Faults
 }
 
-type Option interface {
-	apply(*Server) error
+type Server struct {
+	config      Config
+	build       BuildInfo
+	instanceID  string
+	started     time.Time
+	endpoint    *endpoint.Endpoint[OkEvent]
+	recent      *RecentEvents
+	stream      *EventStream
+	faultHeader bool
+	queueDepth  kit.Gauge
+	dropped     kit.Counter
 }
 
-type optionFunc func(*Server) error
+type Option = endpoint.Option[Server]
 
-func (f optionFunc) apply(s *Server) error {
-	return f(s)
-}
+type optionFunc = endpoint.OptionFunc[Server]
 
 func New(opts ...Option) (*Server, error) {
-	var s Server
+	s := Server{
+		started: time.Now(),
+	}
+	s.endpoint = endpoint.New(s.serve)
+
+	if err := endpoint.Apply(&s, opts...); err != nil {
+		return nil, err
+	}
 
-	for _, opt := range opts {
-		if opt != nil {
-			if err := opt.apply(&s); err != nil {
-				return nil, err
-			}
+	if err := s.config.Faults.validate(); err != nil {
+		return nil, err
+	}
+
+	s.instanceID = s.config.InstanceID
+	if s.instanceID == "" {
+		var buf [8]byte
+		if _, err := rand.Read(buf[:]); err != nil {
+			return nil, err
 		}
+		s.instanceID = hex.EncodeToString(buf[:])
+	}
+
+	if s.config.Recent.Capacity > 0 {
+		s.recent = NewRecentEvents(s.config.Recent.Capacity)
+		s.endpoint.Add(s.recent.OnOkEvent)
+	}
+
+	if s.config.Stream.BufferSize > 0 {
+		s.stream = NewEventStream(s.config.Stream)
+		s.endpoint.Add(s.stream.OnOkEvent)
+	}
+
+	err := s.endpoint.Async(s.config.Dispatch, s.queueDepth, s.dropped)
+	if err != nil {
+		return nil, err
 	}
 
 	return &s, nil
 }
 
+// RecentEvents returns the buffer of recent events, or nil if recent events
+// are not kept.
+func (s *Server) RecentEvents() *RecentEvents {
+	return s.recent
+}
+
+// EventStream returns the live stream of events, or nil if the stream is
+// disabled.
+func (s *Server) EventStream() *EventStream {
+	return s.stream
+}
+
+// Stop stops accepting events and waits for any queued events to be delivered
+// to the listeners or the context to end.  The stream subscribers are
+// disconnected.
+func (s *Server) Stop(ctx context.Context) error {
+	if s.stream != nil {
+		s.stream.Close()
+	}
+
+	return s.endpoint.Stop(ctx)
+}
+
 func (s *Server) ServeHTTP(resp http.ResponseWriter, req *http.Request) {
-	var e OkEvent
+	s.endpoint.ServeHTTP(resp, req)
+}
+
+// serve handles the request and fills in the event.
+func (s *Server) serve(resp http.ResponseWriter, req *http.Request, e *OkEvent) {
+	*e = OkEvent{
+		At:         time.Now(),
+		PartnerID:  apiauth.PartnerID(req.Context()),
+		Method:     req.Method,
+		Route:      routePattern(req),
+		RemoteAddr: req.RemoteAddr,
+		RequestID:  requestid.Get(req.Context()),
+	}
+	if sc := trace.SpanContextFromContext(req.Context()); sc.IsValid() {
+		e.TraceID = sc.TraceID().String()
+		e.SpanID = sc.SpanID().String()
+	}
+	defer func() {
+		e.Duration = time.Since(e.At)
+	}()
+
+	f, err := s.fault(req)
+	if err != nil {
+		e.StatusCode = http.StatusBadRequest
+		e.Err = err
+		http.Error(resp, err.Error(), e.StatusCode)
+		return
+	}
+
+	f.wait(req.Context())
+
+	switch {
+	case f.reset:
+		e.Err = f.err()
+		resetConn(resp)
+		return
+	case f.statusCode != 0:
+		e.StatusCode = f.statusCode
+		e.Err = f.err()
+		resp.Header().Set("Cache-Control", "no-store")
+		http.Error(resp, http.StatusText(e.StatusCode), e.StatusCode)
+		return
+	}
 
-	e.At = time.Now()
-	e.StatusCode = http.StatusOK
-	resp.WriteHeader(http.StatusOK)
+	contentType := negotiate(req.Header.Get("Accept"))
+	body, err := s.response(e.At).marshal(contentType)
+	if err != nil {
+		e.StatusCode = http.StatusInternalServerError
+		e.Err = err
+		resp.WriteHeader(e.StatusCode)
+	} else {
+		e.StatusCode = http.StatusOK
+		resp.Header().Set("Content-Type", contentType+"; charset=utf-8")
+		resp.Header().Set("Cache-Control", "no-store")
+		resp.WriteHeader(e.StatusCode)
+		_, e.Err = resp.Write(body)
+	}
+
+	// The request is reported as failed if the client went away.
+	if e.Err == nil {
+		e.Err = req.Context().Err()
+	}
+}
+
+// fault returns the faults to inject into the request.
+func (s *Server) fault(req *http.Request) (fault, error) {
+	if s.faultHeader {
+		if v := req.Header.Get(FaultHeader); v != "" {
+			return parseFaultHeader(v)
+		}
+	}
+
+	return s.config.Faults.pick(), nil
+}
+
+// routePattern returns the route pattern that matched the request, or the
+// request path if the request was not routed.
+func routePattern(req *http.Request) string {
+	if rctx := chi.RouteContext(req.Context()); rctx != nil {
+		if pattern := rctx.RoutePattern(); pattern != "" {
+			return pattern
+		}
+	}
 
-	s.okEventListeners.Visit(func(listener OkEventListener) {
-		listener.OnOkEvent(e)
-	})
+	return req.URL.Path
 }
@@ -0,0 +1,221 @@
+// SPDX-FileCopyrightText: 2024 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package oker
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xmidt-org/skeleton/internal/apiauth"
+	"github.com/xmidt-org/skeleton/internal/apiauth/apiauthtest"
+	"github.com/xmidt-org/skeleton/internal/endpoint"
+	"github.com/xmidt-org/skeleton/internal/requestid"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestServeHTTP(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	keys, err := apiauthtest.New()
+	require.NoError(err)
+	defer keys.Close()
+
+	auth, err := apiauth.New(apiauth.WithConfig(keys.Config()))
+	require.NoError(err)
+
+	var events []OkEvent
+	s, err := New(
+		WithConfig(Config{Name: "test"}),
+		AddOkEventListener(OkEventListenerFunc(func(e OkEvent) {
+			events = append(events, e)
+		})),
+	)
+	require.NoError(err)
+
+	mux := chi.NewMux()
+	mux.Method("GET", "/api/{version}/ok", auth.Then(s.ServeHTTP))
+
+	token, err := keys.Mint(apiauthtest.Claims{
+		apiauth.AllowedResourcesKey: map[string]any{
+			apiauth.AllowedPartnersKey: []string{"comcast", "other"},
+		},
+	})
+	require.NoError(err)
+
+	req := httptest.NewRequest("GET", "/api/v1/ok", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.RemoteAddr = "10.0.0.1:1234"
+	req = req.WithContext(requestid.With(req.Context(), "abc123"))
+	resp := httptest.NewRecorder()
+	mux.ServeHTTP(resp, req)
+
+	assert.Equal(http.StatusOK, resp.Code)
+	require.Len(events, 1)
+
+	e := events[0]
+	assert.False(e.At.IsZero())
+	assert.Positive(e.Duration)
+	assert.Equal(http.StatusOK, e.StatusCode)
+	assert.Equal("comcast", e.PartnerID)
+	assert.Equal("GET", e.Method)
+	assert.Equal("/api/{version}/ok", e.Route)
+	assert.Equal("10.0.0.1:1234", e.RemoteAddr)
+	assert.Equal("abc123", e.RequestID)
+	assert.NoError(e.Err)
+	assert.Contains(e.String(), `"partner_id":"comcast"`)
+}
+
+func TestServeHTTPCanceled(t *testing.T) {
+	var events []OkEvent
+	s, err := New(
+		AddOkEventListener(OkEventListenerFunc(func(e OkEvent) {
+			events = append(events, e)
+		})),
+	)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	req := httptest.NewRequest("GET", "/ok", nil).WithContext(ctx)
+	s.ServeHTTP(httptest.NewRecorder(), req)
+
+	require.Len(t, events, 1)
+	assert.ErrorIs(t, events[0].Err, context.Canceled)
+	assert.Equal(t, "/ok", events[0].Route)
+	assert.Empty(t, events[0].PartnerID)
+	assert.Empty(t, events[0].TraceID)
+}
+
+func TestServeHTTPTraced(t *testing.T) {
+	var events []OkEvent
+	s, err := New(
+		AddOkEventListener(OkEventListenerFunc(func(e OkEvent) {
+			events = append(events, e)
+		})),
+	)
+	require.NoError(t, err)
+
+	traceID, _ := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	spanID, _ := trace.SpanIDFromHex("00f067aa0ba902b7")
+	ctx := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID: traceID,
+		SpanID:  spanID,
+	}))
+
+	req := httptest.NewRequest("GET", "/ok", nil).WithContext(ctx)
+	s.ServeHTTP(httptest.NewRecorder(), req)
+
+	require.Len(t, events, 1)
+	assert.Equal(t, traceID.String(), events[0].TraceID)
+	assert.Equal(t, spanID.String(), events[0].SpanID)
+}
+
+func TestResponseBody(t *testing.T) {
+	s, err := New(
+		WithConfig(Config{Name: "skeleton", InstanceID: "instance-1"}),
+		WithBuildInfo(BuildInfo{
+			Version: "1.2.3",
+			Commit:  "abcdef",
+			Date:    "2024-12-11",
+			BuiltBy: "goreleaser",
+		}),
+	)
+	require.NoError(t, err)
+
+	t.Run("json", func(t *testing.T) {
+		resp := httptest.NewRecorder()
+		s.ServeHTTP(resp, httptest.NewRequest("GET", "/ok", nil))
+
+		assert.Equal(t, http.StatusOK, resp.Code)
+		assert.Equal(t, "application/json; charset=utf-8", resp.Header().Get("Content-Type"))
+
+		var got Response
+		require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &got))
+		assert.Equal(t, "skeleton", got.Name)
+		assert.Equal(t, "1.2.3", got.Version)
+		assert.Equal(t, "abcdef", got.Commit)
+		assert.Equal(t, "2024-12-11", got.Date)
+		assert.Equal(t, "goreleaser", got.BuiltBy)
+		assert.Equal(t, "instance-1", got.InstanceID)
+		assert.NotEmpty(t, got.StartedAt)
+		assert.NotEmpty(t, got.Uptime)
+	})
+
+	t.Run("text", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/ok", nil)
+		req.Header.Set("Accept", "text/plain")
+		resp := httptest.NewRecorder()
+		s.ServeHTTP(resp, req)
+
+		assert.Equal(t, http.StatusOK, resp.Code)
+		assert.Equal(t, "text/plain; charset=utf-8", resp.Header().Get("Content-Type"))
+		assert.Contains(t, resp.Body.String(), "name: skeleton\n")
+		assert.Contains(t, resp.Body.String(), "instanceId: instance-1\n")
+	})
+}
+
+func TestGeneratedInstanceID(t *testing.T) {
+	a, err := New()
+	require.NoError(t, err)
+	b, err := New()
+	require.NoError(t, err)
+
+	assert.NotEmpty(t, a.instanceID)
+	assert.NotEqual(t, a.instanceID, b.instanceID)
+}
+
+func TestNegotiate(t *testing.T) {
+	tests := []struct {
+		accept string
+		want   string
+	}{
+		{accept: "", want: "application/json"},
+		{accept: "*/*", want: "application/json"},
+		{accept: "text/plain", want: "text/plain"},
+		{accept: "text/*", want: "text/plain"},
+		{accept: "application/json", want: "application/json"},
+		{accept: "text/html", want: "application/json"},
+		{accept: "text/plain, application/json", want: "application/json"},
+		{accept: "text/plain, application/json;q=0.5", want: "text/plain"},
+		{accept: "application/json;q=0.1, text/plain;q=0.9", want: "text/plain"},
+		{accept: "text/plain;q=bad, */*;q=0.1", want: "application/json"},
+		{accept: ";;, text/plain", want: "text/plain"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.accept, func(t *testing.T) {
+			assert.Equal(t, tc.want, negotiate(tc.accept))
+		})
+	}
+}
+
+func TestDispatch(t *testing.T) {
+	var count int
+	s, err := New(
+		WithConfig(Config{Dispatch: endpoint.Dispatch{QueueSize: 10}}),
+		AddOkEventListener(OkEventListenerFunc(func(OkEvent) {
+			count++
+		})),
+	)
+	require.NoError(t, err)
+
+	for range 3 {
+		s.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/ok", nil))
+	}
+
+	// Stopping waits for the queued events.
+	require.NoError(t, s.Stop(context.Background()))
+	assert.Equal(t, 3, count)
+
+	_, err = New(WithConfig(Config{Dispatch: endpoint.Dispatch{QueueSize: 1, Overflow: "invalid"}}))
+	assert.ErrorIs(t, err, endpoint.ErrInvalidDispatch)
+}
@@ -3,6 +3,8 @@
 
 package oker
 
+import kit "github.com/go-kit/kit/metrics"
+
 func WithConfig(c Config) Option {
 	return optionFunc(func(s *Server) error {
 		s.config = c
@@ -10,12 +12,39 @@ func WithConfig(c Config) Option {
 	})
 }
 
+// WithBuildInfo sets the build information reported in the response.
+func WithBuildInfo(b BuildInfo) Option {
+	return optionFunc(func(s *Server) error {
+		s.build = b
+		return nil
+	})
+}
+
+// WithDispatchMetrics sets the metrics used when events are delivered
+// asynchronously.
+func WithDispatchMetrics(queueDepth kit.Gauge, dropped kit.Counter) Option {
+	return optionFunc(func(s *Server) error {
+		s.queueDepth = queueDepth
+		s.dropped = dropped
+		return nil
+	})
+}
+
+// WithFaultHeader lets each request choose its faults using the FaultHeader.
+// This should only be enabled in development mode.
+func WithFaultHeader(enabled bool) Option {
+	return optionFunc(func(s *Server) error {
+		s.faultHeader = enabled
+		return nil
+	})
+}
+
 // AddOkListener adds a listener for oking events.  If the optional cancel
 // parameter is provided, it is set to a function that can be used to cancel
 // the listener.
 func AddOkEventListener(listener OkEventListener, cancel ...*func()) Option {
 	return optionFunc(func(s *Server) error {
-		cncl := s.okEventListeners.Add(listener)
+		cncl := s.endpoint.Add(listener.OnOkEvent)
 		if len(cancel) > 0 && cancel[0] != nil {
 			*cancel[0] = cncl
 		}
@@ -0,0 +1,176 @@
+// SPDX-FileCopyrightText: 2024 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package oker
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/xmidt-org/skeleton/internal/endpoint"
+)
+
+var errInvalidQuery = errors.New("invalid query")
+
+// Recent configures the in-memory buffer of the most recent events.
+type Recent struct {
+	// Capacity is the number of events kept.  If this is zero, no events are
+	// kept.
+	Capacity int
+}
+
+// Filter selects events from the recent events buffer.  Zero values match
+// everything.
+type Filter struct {
+	// PartnerID matches events with the partner id.
+	PartnerID string
+
+	// StatusCode matches events with the status code.
+	StatusCode int
+
+	// Outcome matches events with the outcome, either "success" or "failure".
+	Outcome string
+
+	// Since matches events at or after the time.
+	Since time.Time
+
+	// Until matches events before the time.
+	Until time.Time
+
+	// Limit is the most events returned.
+	Limit int
+}
+
+func (f Filter) match(e OkEvent) bool {
+	switch {
+	case f.PartnerID != "" && f.PartnerID != e.PartnerID:
+		return false
+	case f.StatusCode != 0 && f.StatusCode != e.StatusCode:
+		return false
+	case f.Outcome != "" && f.Outcome != e.outcome():
+		return false
+	case !f.Since.IsZero() && e.At.Before(f.Since):
+		return false
+	case !f.Until.IsZero() && !e.At.Before(f.Until):
+		return false
+	}
+	return true
+}
+
+// RecentEvents is an OkEventListener that keeps the most recent events in a
+// ring buffer.  It is also an http.Handler that returns the events as JSON.
+type RecentEvents struct {
+	m      sync.Mutex
+	events []OkEvent
+	next   int
+	full   bool
+}
+
+// NewRecentEvents creates a buffer that keeps the last capacity events.
+func NewRecentEvents(capacity int) *RecentEvents {
+	return &RecentEvents{
+		events: make([]OkEvent, max(capacity, 1)),
+	}
+}
+
+// OnOkEvent adds the event, replacing the oldest event if the buffer is full.
+func (r *RecentEvents) OnOkEvent(e OkEvent) {
+	r.m.Lock()
+	defer r.m.Unlock()
+
+	r.events[r.next] = e
+	r.next++
+	if r.next == len(r.events) {
+		r.next = 0
+		r.full = true
+	}
+}
+
+// Events returns the events that match the filter, newest first.
+func (r *RecentEvents) Events(f Filter) []OkEvent {
+	r.m.Lock()
+	defer r.m.Unlock()
+
+	count := r.next
+	if r.full {
+		count = len(r.events)
+	}
+
+	list := make([]OkEvent, 0, count)
+	for i := range count {
+		e := r.events[(r.next-1-i+len(r.events))%len(r.events)]
+		if !f.match(e) {
+			continue
+		}
+		list = append(list, e)
+		if f.Limit > 0 && len(list) >= f.Limit {
+			break
+		}
+	}
+
+	return list
+}
+
+// ServeHTTP returns the events that match the query as a JSON array, newest
+// first.  The query parameters are partner, status, outcome, since, until
+// and limit.  The times use the RFC 3339 format.
+func (r *RecentEvents) ServeHTTP(resp http.ResponseWriter, req *http.Request) {
+	f, err := parseFilter(req)
+	if err != nil {
+		http.Error(resp, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	body, err := json.Marshal(r.Events(f))
+	if err != nil {
+		http.Error(resp, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	resp.Header().Set("Content-Type", contentTypeJSON+"; charset=utf-8")
+	resp.Header().Set("Cache-Control", "no-store")
+	_, _ = resp.Write(body)
+}
+
+func parseFilter(req *http.Request) (Filter, error) {
+	q := req.URL.Query()
+	f := Filter{
+		PartnerID: q.Get("partner"),
+		Outcome:   q.Get("outcome"),
+	}
+
+	switch f.Outcome {
+	case "", endpoint.Success, endpoint.Failure:
+	default:
+		return Filter{}, fmt.Errorf("%w: outcome must be '%s' or '%s'", errInvalidQuery, endpoint.Success, endpoint.Failure)
+	}
+
+	var err error
+	if v := q.Get("status"); v != "" {
+		if f.StatusCode, err = strconv.Atoi(v); err != nil {
+			return Filter{}, fmt.Errorf("%w: status: %w", errInvalidQuery, err)
+		}
+	}
+	if v := q.Get("limit"); v != "" {
+		if f.Limit, err = strconv.Atoi(v); err != nil || f.Limit < 0 {
+			return Filter{}, fmt.Errorf("%w: limit must be a positive number", errInvalidQuery)
+		}
+	}
+	if v := q.Get("since"); v != "" {
+		if f.Since, err = time.Parse(time.RFC3339, v); err != nil {
+			return Filter{}, fmt.Errorf("%w: since: %w", errInvalidQuery, err)
+		}
+	}
+	if v := q.Get("until"); v != "" {
+		if f.Until, err = time.Parse(time.RFC3339, v); err != nil {
+			return Filter{}, fmt.Errorf("%w: until: %w", errInvalidQuery, err)
+		}
+	}
+
+	return f, nil
+}
@@ -0,0 +1,118 @@
+// SPDX-FileCopyrightText: 2024 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package oker
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xmidt-org/skeleton/internal/endpoint"
+)
+
+func TestRecentEvents(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	r := NewRecentEvents(3)
+	assert.Empty(t, r.Events(Filter{}))
+
+	for i := range 5 {
+		e := OkEvent{
+			At:         start.Add(time.Duration(i) * time.Minute),
+			PartnerID:  "comcast",
+			StatusCode: http.StatusOK,
+		}
+		if i%2 == 1 {
+			e.PartnerID = "sky"
+			e.StatusCode = http.StatusInternalServerError
+			e.Err = errors.New("boom")
+		}
+		r.OnOkEvent(e)
+	}
+
+	minute := func(list []OkEvent) []int {
+		var got []int
+		for _, e := range list {
+			got = append(got, int(e.At.Sub(start)/time.Minute))
+		}
+		return got
+	}
+
+	tests := []struct {
+		name   string
+		filter Filter
+		want   []int
+	}{
+		{name: "all", want: []int{4, 3, 2}},
+		{name: "partner", filter: Filter{PartnerID: "comcast"}, want: []int{4, 2}},
+		{name: "status", filter: Filter{StatusCode: http.StatusInternalServerError}, want: []int{3}},
+		{name: "outcome", filter: Filter{Outcome: endpoint.Success}, want: []int{4, 2}},
+		{name: "since", filter: Filter{Since: start.Add(3 * time.Minute)}, want: []int{4, 3}},
+		{name: "until", filter: Filter{Until: start.Add(3 * time.Minute)}, want: []int{2}},
+		{name: "limit", filter: Filter{Limit: 1}, want: []int{4}},
+		{name: "no match", filter: Filter{PartnerID: "other"}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, minute(r.Events(tc.filter)))
+		})
+	}
+}
+
+func TestRecentEventsServeHTTP(t *testing.T) {
+	s, err := New(WithConfig(Config{Recent: Recent{Capacity: 10}}))
+	require.NoError(t, err)
+
+	r := s.RecentEvents()
+	require.NotNil(t, r)
+
+	r.OnOkEvent(OkEvent{At: time.Now(), PartnerID: "sky", StatusCode: http.StatusOK})
+	s.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/ok", nil))
+
+	tests := []struct {
+		name   string
+		query  string
+		status int
+		count  int
+	}{
+		{name: "all", status: http.StatusOK, count: 2},
+		{name: "partner", query: "?partner=sky", status: http.StatusOK, count: 1},
+		{name: "filters", query: "?status=200&outcome=success&since=2000-01-01T00:00:00Z&limit=1", status: http.StatusOK, count: 1},
+		{name: "until", query: "?until=2000-01-01T00:00:00Z", status: http.StatusOK, count: 0},
+		{name: "bad status", query: "?status=ok", status: http.StatusBadRequest},
+		{name: "bad outcome", query: "?outcome=maybe", status: http.StatusBadRequest},
+		{name: "bad time", query: "?since=yesterday", status: http.StatusBadRequest},
+		{name: "bad limit", query: "?limit=-1", status: http.StatusBadRequest},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, httptest.NewRequest("GET", "/events"+tc.query, nil))
+
+			require.Equal(t, tc.status, rec.Code)
+			if tc.status != http.StatusOK {
+				return
+			}
+
+			assert.Equal(t, "application/json; charset=utf-8", rec.Header().Get("Content-Type"))
+
+			var events []map[string]any
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &events))
+			assert.Len(t, events, tc.count)
+		})
+	}
+}
+
+func TestRecentEventsDisabled(t *testing.T) {
+	s, err := New()
+	require.NoError(t, err)
+	assert.Nil(t, s.RecentEvents())
+}
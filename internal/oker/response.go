@@ -0,0 +1,108 @@
+// SPDX-FileCopyrightText: 2024 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package oker
+
+import (
+	"encoding/json"
+	"fmt"
+	"mime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	contentTypeJSON = "application/json"
+	contentTypeText = "text/plain"
+)
+
+// BuildInfo describes the build of the running service.  The values match
+// what goreleaser provides.
+type BuildInfo struct {
+	Version string
+	Commit  string
+	Date    string
+	BuiltBy string
+}
+
+// Response is the body returned by the oker endpoint.
+type Response struct {
+	Name       string `json:"name"`
+	Version    string `json:"version"`
+	Commit     string `json:"commit"`
+	Date       string `json:"date"`
+	BuiltBy    string `json:"builtBy"`
+	InstanceID string `json:"instanceId"`
+	StartedAt  string `json:"startedAt"`
+	Uptime     string `json:"uptime"`
+}
+
+// response builds the response body as of now.
+func (s *Server) response(now time.Time) Response {
+	return Response{
+		Name:       s.config.Name,
+		Version:    s.build.Version,
+		Commit:     s.build.Commit,
+		Date:       s.build.Date,
+		BuiltBy:    s.build.BuiltBy,
+		InstanceID: s.instanceID,
+		StartedAt:  s.started.UTC().Format(time.RFC3339),
+		Uptime:     now.Sub(s.started).Truncate(time.Second).String(),
+	}
+}
+
+func (r Response) marshal(contentType string) ([]byte, error) {
+	if contentType == contentTypeJSON {
+		return json.Marshal(r)
+	}
+
+	buf := strings.Builder{}
+	buf.WriteString(fmt.Sprintf("name: %s\n", r.Name))
+	buf.WriteString(fmt.Sprintf("version: %s\n", r.Version))
+	buf.WriteString(fmt.Sprintf("commit: %s\n", r.Commit))
+	buf.WriteString(fmt.Sprintf("date: %s\n", r.Date))
+	buf.WriteString(fmt.Sprintf("builtBy: %s\n", r.BuiltBy))
+	buf.WriteString(fmt.Sprintf("instanceId: %s\n", r.InstanceID))
+	buf.WriteString(fmt.Sprintf("startedAt: %s\n", r.StartedAt))
+	buf.WriteString(fmt.Sprintf("uptime: %s\n", r.Uptime))
+
+	return []byte(buf.String()), nil
+}
+
+// negotiate picks the response content type based on the Accept header.  JSON
+// is preferred and used if the header is missing or nothing matches.
+func negotiate(accept string) string {
+	best, bestQ := contentTypeJSON, 0.0
+
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+
+		q := 1.0
+		if v, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(v, 64); err != nil {
+				continue
+			}
+		}
+
+		var candidate string
+		switch mediaType {
+		case contentTypeJSON, "application/*", "*/*":
+			candidate = contentTypeJSON
+		case contentTypeText, "text/*":
+			candidate = contentTypeText
+		default:
+			continue
+		}
+
+		// Ties go to JSON.
+		if q > bestQ || (q == bestQ && candidate == contentTypeJSON) {
+			best, bestQ = candidate, q
+		}
+	}
+
+	return best
+}
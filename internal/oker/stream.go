@@ -0,0 +1,179 @@
+// SPDX-FileCopyrightText: 2024 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package oker
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const defaultStreamKeepAlive = 15 * time.Second
+
+// Stream configures the live stream of events.
+type Stream struct {
+	// BufferSize is the number of events held for each subscriber.  A
+	// subscriber that falls further behind is disconnected.  If this is zero,
+	// the stream is disabled.
+	BufferSize int
+
+	// KeepAlive is how often a comment is sent to an idle subscriber so the
+	// connection is not closed by proxies.  Defaults to 15s.
+	KeepAlive time.Duration
+}
+
+// subscriber is a single client of the stream.
+type subscriber struct {
+	filter Filter
+	events chan OkEvent
+
+	// gone is closed when the stream disconnects the subscriber.
+	gone chan struct{}
+}
+
+// EventStream is an OkEventListener that sends the events to subscribers as
+// Server-Sent Events as they happen.
+type EventStream struct {
+	bufferSize int
+	keepAlive  time.Duration
+
+	m           sync.Mutex
+	closed      bool
+	subscribers map[*subscriber]struct{}
+}
+
+// NewEventStream creates a stream using the configuration.
+func NewEventStream(cfg Stream) *EventStream {
+	if cfg.KeepAlive <= 0 {
+		cfg.KeepAlive = defaultStreamKeepAlive
+	}
+
+	return &EventStream{
+		bufferSize:  max(cfg.BufferSize, 1),
+		keepAlive:   cfg.KeepAlive,
+		subscribers: make(map[*subscriber]struct{}),
+	}
+}
+
+// OnOkEvent sends the event to the subscribers that want it without blocking.
+// Subscribers that have no room for the event are disconnected.
+func (es *EventStream) OnOkEvent(e OkEvent) {
+	es.m.Lock()
+	defer es.m.Unlock()
+
+	for sub := range es.subscribers {
+		if !sub.filter.match(e) {
+			continue
+		}
+
+		select {
+		case sub.events <- e:
+		default:
+			es.remove(sub)
+		}
+	}
+}
+
+// Close disconnects all subscribers and rejects new ones.
+func (es *EventStream) Close() {
+	es.m.Lock()
+	defer es.m.Unlock()
+
+	es.closed = true
+	for sub := range es.subscribers {
+		es.remove(sub)
+	}
+}
+
+func (es *EventStream) subscribe(f Filter) *subscriber {
+	es.m.Lock()
+	defer es.m.Unlock()
+
+	if es.closed {
+		return nil
+	}
+
+	sub := subscriber{
+		filter: f,
+		events: make(chan OkEvent, es.bufferSize),
+		gone:   make(chan struct{}),
+	}
+	es.subscribers[&sub] = struct{}{}
+
+	return &sub
+}
+
+func (es *EventStream) unsubscribe(sub *subscriber) {
+	es.m.Lock()
+	defer es.m.Unlock()
+
+	delete(es.subscribers, sub)
+}
+
+// remove must be called with the lock held.
+func (es *EventStream) remove(sub *subscriber) {
+	delete(es.subscribers, sub)
+	close(sub.gone)
+}
+
+// ServeHTTP streams the events that match the query until the client goes
+// away, falls behind or the stream is closed.  The query parameters are the
+// same as RecentEvents; if a limit is given the stream ends after that many
+// events.
+func (es *EventStream) ServeHTTP(resp http.ResponseWriter, req *http.Request) {
+	f, err := parseFilter(req)
+	if err != nil {
+		http.Error(resp, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	sub := es.subscribe(f)
+	if sub == nil {
+		http.Error(resp, "the event stream is closed", http.StatusServiceUnavailable)
+		return
+	}
+	defer es.unsubscribe(sub)
+
+	rc := http.NewResponseController(resp)
+
+	resp.Header().Set("Content-Type", "text/event-stream")
+	resp.Header().Set("Cache-Control", "no-store")
+	resp.WriteHeader(http.StatusOK)
+	if err := rc.Flush(); err != nil {
+		return
+	}
+
+	keepAlive := time.NewTicker(es.keepAlive)
+	defer keepAlive.Stop()
+
+	for sent := 0; f.Limit == 0 || sent < f.Limit; {
+		select {
+		case e := <-sub.events:
+			data, err := e.MarshalJSON()
+			if err != nil {
+				return
+			}
+			if _, err = fmt.Fprintf(resp, "event: ok\ndata: %s\n\n", data); err != nil {
+				return
+			}
+			sent++
+
+		case <-keepAlive.C:
+			if _, err := fmt.Fprint(resp, ": keep-alive\n\n"); err != nil {
+				return
+			}
+
+		case <-sub.gone:
+			return
+
+		case <-req.Context().Done():
+			return
+		}
+
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
@@ -0,0 +1,176 @@
+// SPDX-FileCopyrightText: 2024 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package oker
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// count reports the number of connected subscribers.
+func (es *EventStream) count() int {
+	es.m.Lock()
+	defer es.m.Unlock()
+	return len(es.subscribers)
+}
+
+func newStreamServer(t *testing.T, es *EventStream) *httptest.Server {
+	srv := httptest.NewServer(es)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func connect(t *testing.T, es *EventStream, url string) (*bufio.Scanner, func()) {
+	before := es.count()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	require.NoError(t, err)
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	require.Eventually(t, func() bool {
+		return es.count() == before+1
+	}, time.Second, time.Millisecond)
+
+	return bufio.NewScanner(resp.Body), func() {
+		cancel()
+		resp.Body.Close()
+	}
+}
+
+// next returns the data of the next event in the stream.
+func next(t *testing.T, sc *bufio.Scanner) map[string]any {
+	for sc.Scan() {
+		line := sc.Text()
+		if data, ok := strings.CutPrefix(line, "data: "); ok {
+			var e map[string]any
+			require.NoError(t, json.Unmarshal([]byte(data), &e))
+			return e
+		}
+	}
+	require.Fail(t, "the stream ended")
+	return nil
+}
+
+func TestEventStream(t *testing.T) {
+	es := NewEventStream(Stream{BufferSize: 10})
+	srv := newStreamServer(t, es)
+
+	all, closeAll := connect(t, es, srv.URL)
+	defer closeAll()
+	sky, closeSky := connect(t, es, srv.URL+"?partner=sky&outcome=failure")
+	defer closeSky()
+
+	es.OnOkEvent(OkEvent{PartnerID: "comcast", StatusCode: http.StatusOK})
+	es.OnOkEvent(OkEvent{PartnerID: "sky", StatusCode: http.StatusOK})
+	es.OnOkEvent(OkEvent{PartnerID: "sky", StatusCode: http.StatusInternalServerError, Err: errors.New("boom")})
+
+	assert.Equal(t, "comcast", next(t, all)["partner_id"])
+	assert.Equal(t, "sky", next(t, all)["partner_id"])
+	assert.Equal(t, "failure", next(t, all)["outcome"])
+
+	e := next(t, sky)
+	assert.Equal(t, "sky", e["partner_id"])
+	assert.Equal(t, "boom", e["error"])
+
+	// Disconnected clients are removed.
+	closeSky()
+	assert.Eventually(t, func() bool {
+		return es.count() == 1
+	}, time.Second, time.Millisecond)
+}
+
+func TestEventStreamLimit(t *testing.T) {
+	es := NewEventStream(Stream{BufferSize: 10})
+	srv := newStreamServer(t, es)
+
+	sc, done := connect(t, es, srv.URL+"?limit=1")
+	defer done()
+
+	es.OnOkEvent(OkEvent{PartnerID: "first"})
+	es.OnOkEvent(OkEvent{PartnerID: "second"})
+
+	assert.Equal(t, "first", next(t, sc)["partner_id"])
+	for sc.Scan() {
+		assert.NotContains(t, sc.Text(), "second")
+	}
+	assert.Equal(t, 0, es.count())
+}
+
+func TestEventStreamSlowConsumer(t *testing.T) {
+	es := NewEventStream(Stream{BufferSize: 2})
+
+	// The subscriber never reads, so the buffer fills up.
+	sub := es.subscribe(Filter{})
+	require.NotNil(t, sub)
+
+	for range 3 {
+		es.OnOkEvent(OkEvent{StatusCode: http.StatusOK})
+	}
+
+	select {
+	case <-sub.gone:
+	default:
+		assert.Fail(t, "the slow subscriber was not disconnected")
+	}
+	assert.Equal(t, 0, es.count())
+}
+
+func TestEventStreamKeepAlive(t *testing.T) {
+	es := NewEventStream(Stream{BufferSize: 1, KeepAlive: time.Millisecond})
+	srv := newStreamServer(t, es)
+
+	sc, done := connect(t, es, srv.URL)
+	defer done()
+
+	require.True(t, sc.Scan())
+	assert.Equal(t, ": keep-alive", sc.Text())
+}
+
+func TestEventStreamClose(t *testing.T) {
+	s, err := New(WithConfig(Config{Stream: Stream{BufferSize: 1}}))
+	require.NoError(t, err)
+
+	es := s.EventStream()
+	require.NotNil(t, es)
+	srv := newStreamServer(t, es)
+
+	sc, done := connect(t, es, srv.URL)
+	defer done()
+
+	require.NoError(t, s.Stop(context.Background()))
+
+	// The stream ends and new subscribers are rejected.
+	for sc.Scan() {
+	}
+	resp, err := http.Get(srv.URL)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+
+	resp, err = http.Get(srv.URL + "?outcome=maybe")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
+
+func TestEventStreamDisabled(t *testing.T) {
+	s, err := New()
+	require.NoError(t, err)
+	assert.Nil(t, s.EventStream())
+}
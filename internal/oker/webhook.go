@@ -0,0 +1,280 @@
+// SPDX-FileCopyrightText: 2024 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package oker
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	kit "github.com/go-kit/kit/metrics"
+	"github.com/xmidt-org/arrange/arrangehttp"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultWebhookMaxBatchSize = 100
+	defaultWebhookMaxBatchAge  = 5 * time.Second
+	defaultWebhookQueueSize    = 1000
+	defaultWebhookMaxRetries   = 3
+	defaultWebhookBackoff      = 500 * time.Millisecond
+	defaultWebhookMaxBackoff   = 30 * time.Second
+)
+
+var (
+	errInvalidWebhook = errors.New("invalid webhook config")
+	errWebhookStopped = errors.New("webhook stopped")
+)
+
+// Webhook configures a sink that POSTs batches of events as a JSON array to
+// a URL.
+type Webhook struct {
+	// URL is where the events are sent.
+	URL string
+
+	// MaxBatchSize is the most events sent in one request.  Defaults to 100.
+	MaxBatchSize int
+
+	// MaxBatchAge is the longest an event waits before being sent.  Defaults
+	// to 5s.
+	MaxBatchAge time.Duration
+
+	// QueueSize is the number of events that can wait to be batched.  Events
+	// are dropped if the queue is full, and the drops are counted and logged.
+	// Defaults to 1000.
+	QueueSize int
+
+	// MaxRetries is the number of times a failed request is retried.
+	// Defaults to 3.  Set to a negative value to disable retries.
+	MaxRetries int
+
+	// Backoff is the wait before the first retry.  The wait doubles with each
+	// retry up to MaxBackoff.  Defaults to 500ms and 30s.
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+
+	// HTTPClient is the configuration for the http client.  Auth headers (for
+	// example Authorization) can be added using HTTPClient.Header.  Without
+	// an Authorization header the outbound credentials are sent, if they are
+	// configured.
+	HTTPClient arrangehttp.ClientConfig
+}
+
+// webhook is an OkEventListener that batches events and sends them to a URL.
+type webhook struct {
+	config Webhook
+	client *http.Client
+	logger *zap.Logger
+	drops  *drops
+
+	events chan OkEvent
+	stop   chan struct{}
+	done   chan struct{}
+
+	// ctx is canceled when the shutdown deadline passes so that pending
+	// requests and retries are abandoned.
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+func newWebhook(cfg Webhook, logger *zap.Logger, dropped kit.Counter, opts ...arrangehttp.Option[http.Client]) (*webhook, error) {
+	if _, err := url.ParseRequestURI(cfg.URL); err != nil {
+		return nil, fmt.Errorf("%w: %w", errInvalidWebhook, err)
+	}
+
+	if cfg.MaxBatchSize <= 0 {
+		cfg.MaxBatchSize = defaultWebhookMaxBatchSize
+	}
+	if cfg.MaxBatchAge <= 0 {
+		cfg.MaxBatchAge = defaultWebhookMaxBatchAge
+	}
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = defaultWebhookQueueSize
+	}
+	if cfg.MaxRetries == 0 {
+		cfg.MaxRetries = defaultWebhookMaxRetries
+	}
+	if cfg.Backoff <= 0 {
+		cfg.Backoff = defaultWebhookBackoff
+	}
+	if cfg.MaxBackoff <= 0 {
+		cfg.MaxBackoff = defaultWebhookMaxBackoff
+	}
+
+	client, err := arrangehttp.NewClient(cfg.HTTPClient, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+
+	logger = logger.With(zap.String("webhook", cfg.URL))
+	w := webhook{
+		config: cfg,
+		client: client,
+		logger: logger,
+		drops:  newDrops("webhook", dropped, logger, "webhook events dropped"),
+		events: make(chan OkEvent, cfg.QueueSize),
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	w.ctx, w.cancel = context.WithCancel(context.Background())
+
+	go w.run()
+
+	return &w, nil
+}
+
+// OnOkEvent queues the event without blocking.  Events that arrive when the
+// queue is full or after the webhook is stopped are dropped.
+func (w *webhook) OnOkEvent(e OkEvent) {
+	select {
+	case <-w.stop:
+		w.drops.add()
+		return
+	default:
+	}
+
+	select {
+	case w.events <- e:
+	default:
+		w.drops.add()
+	}
+}
+
+// Stop sends any queued events and stops the webhook.  If the context ends
+// first, the remaining events are abandoned.
+func (w *webhook) Stop(ctx context.Context) error {
+	select {
+	case <-w.stop:
+	default:
+		close(w.stop)
+	}
+
+	select {
+	case <-w.done:
+		return nil
+	case <-ctx.Done():
+		w.cancel()
+		<-w.done
+		return ctx.Err()
+	}
+}
+
+func (w *webhook) run() {
+	defer close(w.done)
+	defer w.cancel()
+
+	batch := make([]OkEvent, 0, w.config.MaxBatchSize)
+	timer := time.NewTimer(w.config.MaxBatchAge)
+	timer.Stop()
+
+	flush := func() {
+		timer.Stop()
+		if len(batch) > 0 {
+			w.send(batch)
+			batch = batch[:0]
+		}
+	}
+
+	for {
+		select {
+		case e := <-w.events:
+			if len(batch) == 0 {
+				timer.Reset(w.config.MaxBatchAge)
+			}
+			batch = append(batch, e)
+			if len(batch) >= w.config.MaxBatchSize {
+				flush()
+			}
+
+		case <-timer.C:
+			flush()
+
+		case <-w.stop:
+			for {
+				select {
+				case e := <-w.events:
+					batch = append(batch, e)
+					if len(batch) >= w.config.MaxBatchSize {
+						flush()
+					}
+				default:
+					flush()
+					return
+				}
+			}
+		}
+	}
+}
+
+// send posts the batch, retrying with backoff on failures that may succeed
+// later.
+func (w *webhook) send(batch []OkEvent) {
+	body, err := json.Marshal(batch)
+	if err != nil {
+		w.logger.Error("unable to marshal webhook events", zap.Error(err))
+		return
+	}
+
+	backoff := w.config.Backoff
+	for attempt := 0; ; attempt++ {
+		retry, err := w.post(body)
+		if err == nil {
+			return
+		}
+
+		if !retry || attempt >= w.config.MaxRetries {
+			w.logger.Error("unable to send webhook events",
+				zap.Int("events", len(batch)),
+				zap.Int("attempts", attempt+1),
+				zap.Error(err))
+			return
+		}
+
+		select {
+		case <-time.After(backoff):
+		case <-w.ctx.Done():
+			w.logger.Error("unable to send webhook events",
+				zap.Int("events", len(batch)),
+				zap.Error(errors.Join(err, errWebhookStopped)))
+			return
+		}
+
+		backoff = min(backoff*2, w.config.MaxBackoff)
+	}
+}
+
+// post sends the body once and reports if a failure can be retried.
+func (w *webhook) post(body []byte) (retry bool, err error) {
+	req, err := http.NewRequestWithContext(w.ctx, http.MethodPost, w.config.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return false, nil
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		return true, fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	default:
+		return false, fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+}
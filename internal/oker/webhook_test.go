@@ -0,0 +1,218 @@
+// SPDX-FileCopyrightText: 2024 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package oker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xmidt-org/arrange/arrangehttp"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+type receiver struct {
+	*httptest.Server
+
+	m        sync.Mutex
+	batches  [][]map[string]any
+	headers  []http.Header
+	failures int
+	status   int
+}
+
+func newReceiver(t *testing.T) *receiver {
+	r := &receiver{}
+	r.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		r.m.Lock()
+		defer r.m.Unlock()
+
+		if r.failures > 0 {
+			r.failures--
+			w.WriteHeader(r.status)
+			return
+		}
+
+		var batch []map[string]any
+		assert.NoError(t, json.NewDecoder(req.Body).Decode(&batch))
+		r.batches = append(r.batches, batch)
+		r.headers = append(r.headers, req.Header.Clone())
+	}))
+	t.Cleanup(r.Close)
+	return r
+}
+
+func (r *receiver) received() [][]map[string]any {
+	r.m.Lock()
+	defer r.m.Unlock()
+	return append([][]map[string]any{}, r.batches...)
+}
+
+func (r *receiver) fail(n, status int) {
+	r.m.Lock()
+	defer r.m.Unlock()
+	r.failures = n
+	r.status = status
+}
+
+func TestWebhookBatchSize(t *testing.T) {
+	r := newReceiver(t)
+	w, err := newWebhook(Webhook{
+		URL:          r.URL,
+		MaxBatchSize: 2,
+		MaxBatchAge:  time.Hour,
+		HTTPClient: arrangehttp.ClientConfig{
+			Header: http.Header{"Authorization": {"Bearer secret"}},
+		},
+	}, nil, nil)
+	require.NoError(t, err)
+
+	for i := range 5 {
+		w.OnOkEvent(OkEvent{
+			At:         time.Now(),
+			PartnerID:  "comcast",
+			Duration:   time.Duration(i) * time.Millisecond,
+			StatusCode: http.StatusOK,
+		})
+	}
+	w.OnOkEvent(OkEvent{StatusCode: http.StatusInternalServerError, Err: errors.New("boom")})
+
+	require.Eventually(t, func() bool {
+		return len(r.received()) == 3
+	}, time.Second, time.Millisecond)
+
+	require.NoError(t, w.Stop(context.Background()))
+
+	batches := r.received()
+	require.Len(t, batches, 3)
+	for _, b := range batches {
+		assert.Len(t, b, 2)
+	}
+	assert.Equal(t, "comcast", batches[0][0]["partner_id"])
+	assert.Equal(t, 1.0, batches[0][1]["duration_ms"])
+	assert.Equal(t, "boom", batches[2][1]["error"])
+	assert.Equal(t, "Bearer secret", r.headers[0].Get("Authorization"))
+	assert.Equal(t, "application/json", r.headers[0].Get("Content-Type"))
+}
+
+func TestWebhookBatchAge(t *testing.T) {
+	r := newReceiver(t)
+	w, err := newWebhook(Webhook{
+		URL:         r.URL,
+		MaxBatchAge: 10 * time.Millisecond,
+	}, nil, nil)
+	require.NoError(t, err)
+	defer w.Stop(context.Background())
+
+	w.OnOkEvent(OkEvent{StatusCode: http.StatusOK})
+
+	require.Eventually(t, func() bool {
+		return len(r.received()) == 1
+	}, time.Second, time.Millisecond)
+}
+
+func TestWebhookRetry(t *testing.T) {
+	tests := []struct {
+		name     string
+		failures int
+		status   int
+		want     int
+	}{
+		{name: "retried", failures: 2, status: http.StatusServiceUnavailable, want: 1},
+		{name: "too many failures", failures: 5, status: http.StatusServiceUnavailable, want: 0},
+		{name: "not retryable", failures: 1, status: http.StatusBadRequest, want: 0},
+		{name: "rate limited", failures: 1, status: http.StatusTooManyRequests, want: 1},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			r := newReceiver(t)
+			r.fail(tc.failures, tc.status)
+
+			w, err := newWebhook(Webhook{
+				URL:         r.URL,
+				MaxBatchAge: time.Hour,
+				MaxRetries:  2,
+				Backoff:     time.Millisecond,
+			}, nil, nil)
+			require.NoError(t, err)
+
+			w.OnOkEvent(OkEvent{StatusCode: http.StatusOK})
+			require.NoError(t, w.Stop(context.Background()))
+
+			assert.Len(t, r.received(), tc.want)
+		})
+	}
+}
+
+func TestWebhookStopTimeout(t *testing.T) {
+	r := newReceiver(t)
+	r.fail(100, http.StatusServiceUnavailable)
+
+	w, err := newWebhook(Webhook{
+		URL:         r.URL,
+		MaxBatchAge: time.Hour,
+		MaxRetries:  100,
+		Backoff:     time.Hour,
+	}, nil, nil)
+	require.NoError(t, err)
+
+	w.OnOkEvent(OkEvent{StatusCode: http.StatusOK})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, w.Stop(ctx), context.DeadlineExceeded)
+}
+
+func TestWebhookOverflow(t *testing.T) {
+	core, logs := observer.New(zap.WarnLevel)
+	dropped := newCounter()
+
+	// Without the sender running the queue fills up.
+	w := webhook{
+		events: make(chan OkEvent, 1),
+		stop:   make(chan struct{}),
+		drops:  newDrops("webhook", dropped, zap.New(core), "webhook events dropped"),
+	}
+	for range 5 {
+		w.OnOkEvent(OkEvent{StatusCode: http.StatusOK})
+	}
+
+	assert.Equal(t, map[string]float64{"sink,webhook": 4}, dropped.values)
+	assert.Equal(t, 1, logs.FilterMessage("webhook events dropped").Len())
+}
+
+func TestWebhookAfterStop(t *testing.T) {
+	var received atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(resp http.ResponseWriter, _ *http.Request) {
+		received.Add(1)
+		resp.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	dropped := newCounter()
+	w, err := newWebhook(Webhook{URL: server.URL}, nil, dropped)
+	require.NoError(t, err)
+	require.NoError(t, w.Stop(context.Background()))
+
+	// Nothing is left to send the events, so they are dropped.
+	w.OnOkEvent(OkEvent{StatusCode: http.StatusOK})
+	assert.Empty(t, w.events)
+	assert.Equal(t, map[string]float64{"sink,webhook": 1}, dropped.values)
+	assert.Zero(t, received.Load())
+}
+
+func TestWebhookInvalid(t *testing.T) {
+	_, err := newWebhook(Webhook{URL: "not a url"}, nil, nil)
+	assert.ErrorIs(t, err, errInvalidWebhook)
+}
@@ -0,0 +1,28 @@
+// SPDX-FileCopyrightText: 2024 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package recovery
+
+import (
+	kit "github.com/go-kit/kit/metrics"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+)
+
+type RecoveryIn struct {
+	fx.In
+	Logger  *zap.Logger
+	Counter kit.Counter `name:"server_handler_error_count"`
+}
+
+// Module provides the *Recovery.
+var Module = fx.Module("recovery",
+	fx.Provide(
+		func(in RecoveryIn) (*Recovery, error) {
+			return New(
+				WithLogger(in.Logger),
+				WithCounter(in.Counter),
+			)
+		},
+	),
+)
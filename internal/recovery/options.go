@@ -0,0 +1,36 @@
+// SPDX-FileCopyrightText: 2024 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package recovery
+
+import (
+	kit "github.com/go-kit/kit/metrics"
+	"go.uber.org/zap"
+)
+
+type Option interface {
+	apply(*Recovery) error
+}
+
+type optionFunc func(*Recovery) error
+
+func (f optionFunc) apply(r *Recovery) error {
+	return f(r)
+}
+
+// WithLogger sets the logger used when the request context has none.
+func WithLogger(logger *zap.Logger) Option {
+	return optionFunc(func(r *Recovery) error {
+		r.logger = logger
+		return nil
+	})
+}
+
+// WithCounter sets the counter of the panics.  It is labeled with the server,
+// route and error.
+func WithCounter(c kit.Counter) Option {
+	return optionFunc(func(r *Recovery) error {
+		r.counter = c
+		return nil
+	})
+}
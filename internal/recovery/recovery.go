@@ -0,0 +1,126 @@
+// SPDX-FileCopyrightText: 2024 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+// Package recovery recovers the panics of the http handlers, so they are
+// logged and counted and the client gets a 500 problem response instead of a
+// closed connection.
+package recovery
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"runtime/debug"
+
+	kit "github.com/go-kit/kit/metrics"
+	"github.com/xmidt-org/sallust"
+	"github.com/xmidt-org/skeleton/internal/log"
+	"github.com/xmidt-org/skeleton/internal/metrics"
+	"github.com/xmidt-org/skeleton/internal/middleware"
+	"github.com/xmidt-org/skeleton/internal/requestid"
+	"go.uber.org/zap"
+)
+
+// Message is the message of the panic log lines.
+const Message = "panic recovered"
+
+// ProblemContentType is the content type of the problem responses.
+const ProblemContentType = "application/problem+json"
+
+// Problem is the RFC 9457 problem details response sent when a handler
+// panics.  RequestID lets the caller report the failed request.
+type Problem struct {
+	Type      string `json:"type"`
+	Title     string `json:"title"`
+	Status    int    `json:"status"`
+	RequestID string `json:"request_id,omitempty"`
+}
+
+// Recovery recovers the panics.
+type Recovery struct {
+	logger  *zap.Logger
+	counter kit.Counter
+}
+
+// New creates the recovery.  Without a logger the panics are only logged
+// when the request context has one, and without a counter they aren't
+// counted.
+func New(opts ...Option) (*Recovery, error) {
+	var r Recovery
+
+	for _, opt := range opts {
+		if opt != nil {
+			if err := opt.apply(&r); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if r.logger == nil {
+		r.logger = zap.NewNop()
+	}
+
+	return &r, nil
+}
+
+// Middleware recovers the panics of the handlers of the named server.  The
+// panic is logged with its stack trace and counted by route.  If the response
+// was already started it can't be replaced, so the connection is aborted
+// instead.  http.ErrAbortHandler is passed on untouched since it is how
+// handlers abort on purpose.
+func (r *Recovery) Middleware(server string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+			req, route := middleware.TrackRoute(req)
+			rec := middleware.NewRecorder(resp)
+
+			defer func() {
+				v := recover()
+				if v == nil {
+					return
+				}
+				if err, ok := v.(error); ok && errors.Is(err, http.ErrAbortHandler) {
+					panic(v)
+				}
+
+				route := route.Pattern()
+				sallust.GetDefault(req.Context(), r.logger).Error(Message,
+					zap.String(log.ServerName, server),
+					zap.String(log.HTTPRoute, route),
+					zap.String(log.ErrorMessage, fmt.Sprint(v)),
+					zap.String(log.ErrorStackTrace, string(debug.Stack())),
+				)
+				if r.counter != nil {
+					r.counter.With(
+						"server", server,
+						"route", metrics.GetUnknownTagIfEmpty(route),
+						"error", metrics.Panic,
+					).Add(1)
+				}
+
+				if rec.Started {
+					panic(http.ErrAbortHandler)
+				}
+
+				writeProblem(resp, req, http.StatusInternalServerError)
+			}()
+
+			next.ServeHTTP(rec, req)
+		})
+	}
+}
+
+func writeProblem(resp http.ResponseWriter, req *http.Request, code int) {
+	b, _ := json.Marshal(Problem{
+		Type:      "about:blank",
+		Title:     http.StatusText(code),
+		Status:    code,
+		RequestID: requestid.Get(req.Context()),
+	})
+
+	resp.Header().Set("Content-Type", ProblemContentType)
+	resp.Header().Set("X-Content-Type-Options", "nosniff")
+	resp.WriteHeader(code)
+	_, _ = resp.Write(b)
+}
@@ -0,0 +1,146 @@
+// SPDX-FileCopyrightText: 2024 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package recovery
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	kit "github.com/go-kit/kit/metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xmidt-org/skeleton/internal/log"
+	"github.com/xmidt-org/skeleton/internal/requestid"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+type counter struct {
+	labels []string
+	values map[string]float64
+}
+
+func (c *counter) With(lvs ...string) kit.Counter {
+	return &counter{
+		labels: append(append([]string{}, c.labels...), lvs...),
+		values: c.values,
+	}
+}
+
+func (c *counter) Add(v float64) {
+	c.values[strings.Join(c.labels, ",")] += v
+}
+
+func newRecovery(t *testing.T) (*Recovery, *observer.ObservedLogs, *counter) {
+	core, logs := observer.New(zap.InfoLevel)
+	c := &counter{values: make(map[string]float64)}
+	r, err := New(WithLogger(zap.New(core)), WithCounter(c))
+	require.NoError(t, err)
+	return r, logs, c
+}
+
+func TestMiddleware(t *testing.T) {
+	r, logs, c := newRecovery(t)
+
+	mux := chi.NewMux()
+	mux.Get("/api/{version}/ok", func(http.ResponseWriter, *http.Request) {
+		panic("boom")
+	})
+	h := r.Middleware("primary")(mux)
+
+	req := httptest.NewRequest("GET", "/api/v1/ok", nil)
+	req = req.WithContext(requestid.With(req.Context(), "abc123"))
+	resp := httptest.NewRecorder()
+	h.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusInternalServerError, resp.Code)
+	assert.Equal(t, ProblemContentType, resp.Header().Get("Content-Type"))
+
+	var p Problem
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &p))
+	assert.Equal(t, Problem{
+		Type:      "about:blank",
+		Title:     "Internal Server Error",
+		Status:    http.StatusInternalServerError,
+		RequestID: "abc123",
+	}, p)
+
+	entries := logs.FilterMessage(Message).All()
+	require.Len(t, entries, 1)
+	fields := entries[0].ContextMap()
+	assert.Equal(t, "boom", fields[log.ErrorMessage])
+	assert.Contains(t, fields[log.ErrorStackTrace], "recovery_test.go")
+	assert.Equal(t, "/api/{version}/ok", fields[log.HTTPRoute])
+
+	assert.Equal(t, map[string]float64{
+		"server,primary,route,/api/{version}/ok,error,panic": 1,
+	}, c.values)
+}
+
+func TestMiddlewareServeMux(t *testing.T) {
+	r, _, c := newRecovery(t)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /debug/{name}", func(http.ResponseWriter, *http.Request) {
+		panic(io.ErrUnexpectedEOF)
+	})
+
+	resp := httptest.NewRecorder()
+	r.Middleware("pprof")(mux).ServeHTTP(resp, httptest.NewRequest("GET", "/debug/heap", nil))
+
+	assert.Equal(t, http.StatusInternalServerError, resp.Code)
+	assert.Equal(t, map[string]float64{
+		"server,pprof,route,GET /debug/{name},error,panic": 1,
+	}, c.values)
+}
+
+func TestMiddlewareStarted(t *testing.T) {
+	r, logs, c := newRecovery(t)
+
+	h := r.Middleware("primary")(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = io.WriteString(w, "partial")
+		panic("boom")
+	}))
+
+	// The response can't be replaced, so the connection is aborted.
+	assert.PanicsWithValue(t, http.ErrAbortHandler, func() {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	})
+	assert.Equal(t, 1, logs.FilterMessage(Message).Len())
+	assert.Equal(t, map[string]float64{
+		"server,primary,route,unknown,error,panic": 1,
+	}, c.values)
+}
+
+func TestMiddlewareAbort(t *testing.T) {
+	r, logs, c := newRecovery(t)
+
+	h := r.Middleware("primary")(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		panic(http.ErrAbortHandler)
+	}))
+
+	assert.PanicsWithValue(t, http.ErrAbortHandler, func() {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	})
+	assert.Zero(t, logs.Len())
+	assert.Empty(t, c.values)
+}
+
+func TestMiddlewareDefaults(t *testing.T) {
+	r, err := New()
+	require.NoError(t, err)
+
+	h := r.Middleware("primary")(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		panic("boom")
+	}))
+
+	resp := httptest.NewRecorder()
+	h.ServeHTTP(resp, httptest.NewRequest("GET", "/", nil))
+	assert.Equal(t, http.StatusInternalServerError, resp.Code)
+}
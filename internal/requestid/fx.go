@@ -0,0 +1,43 @@
+// SPDX-FileCopyrightText: 2024 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package requestid
+
+import (
+	"net/http"
+
+	"github.com/xmidt-org/arrange/arrangehttp"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+)
+
+type PropagatorIn struct {
+	fx.In
+	Config Config `optional:"true"`
+	Logger *zap.Logger
+}
+
+// Module provides the *Propagator.
+var Module = fx.Module("requestid",
+	fx.Provide(
+		func(in PropagatorIn) (*Propagator, error) {
+			return New(
+				WithConfig(in.Config),
+				WithLogger(in.Logger),
+			)
+		},
+	),
+)
+
+// ProvideClientOption adds the request ids to the client built by
+// arrangehttp.ProvideClient(clientName).
+func ProvideClientOption(clientName string) fx.Option {
+	return fx.Provide(
+		fx.Annotate(
+			func(p *Propagator) arrangehttp.Option[http.Client] {
+				return p.ClientOption()
+			},
+			fx.ResultTags(`group:"`+clientName+`.options"`),
+		),
+	)
+}
@@ -0,0 +1,62 @@
+// SPDX-FileCopyrightText: 2024 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package requestid
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/netip"
+
+	"go.uber.org/zap"
+)
+
+var ErrInvalidConfig = errors.New("invalid request id configuration")
+
+type Option interface {
+	apply(*Propagator) error
+}
+
+type optionFunc func(*Propagator) error
+
+func (f optionFunc) apply(p *Propagator) error {
+	return f(p)
+}
+
+// WithConfig sets the configuration.  The trusted networks must be valid
+// prefixes.
+func WithConfig(c Config) Option {
+	return optionFunc(func(p *Propagator) error {
+		headers := make([]string, 0, len(c.Headers))
+		for _, h := range c.Headers {
+			if h == "" {
+				return fmt.Errorf("%w: empty header", ErrInvalidConfig)
+			}
+			headers = append(headers, http.CanonicalHeaderKey(h))
+		}
+
+		trusted := make([]netip.Prefix, 0, len(c.Trusted))
+		for _, t := range c.Trusted {
+			prefix, err := netip.ParsePrefix(t)
+			if err != nil {
+				return fmt.Errorf("%w: trusted '%s': %w", ErrInvalidConfig, t, err)
+			}
+			trusted = append(trusted, prefix.Masked())
+		}
+
+		p.headers = headers
+		p.trusted = trusted
+		p.maxLength = c.MaxLength
+		return nil
+	})
+}
+
+// WithLogger sets the logger the request-scoped loggers are derived from
+// when the request context has none.
+func WithLogger(logger *zap.Logger) Option {
+	return optionFunc(func(p *Propagator) error {
+		p.logger = logger
+		return nil
+	})
+}
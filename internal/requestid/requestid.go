@@ -0,0 +1,213 @@
+// SPDX-FileCopyrightText: 2024 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+// Package requestid gives every request an id that correlates its logs,
+// events and responses.  The id sent by a trusted caller is used, otherwise a
+// new one is generated.
+package requestid
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"net/netip"
+
+	"github.com/xmidt-org/arrange/arrangehttp"
+	"github.com/xmidt-org/sallust"
+	"github.com/xmidt-org/skeleton/internal/log"
+	"go.uber.org/zap"
+)
+
+const (
+	// DefaultHeader is the header the id is sent in unless configured.
+	DefaultHeader = "X-Request-Id"
+
+	// MidtHeader is the header the Xmidt services send the id in.
+	MidtHeader = "X-Midt-Request-Id"
+
+	// DefaultMaxLength is the longest id accepted unless configured.
+	DefaultMaxLength = 128
+)
+
+// Config is the configuration of the request ids.
+type Config struct {
+	// Headers are the request headers the id is read from, in order.  The
+	// first one is set on the responses and the outgoing requests.  The
+	// default is X-Request-Id and X-Midt-Request-Id.
+	Headers []string
+
+	// Trusted are the networks, such as 10.0.0.0/8, of the callers whose ids
+	// are used.  Other callers get a new id.  No one is trusted by default,
+	// so list only the proxies and services that set the ids; any caller in
+	// these networks can choose the id in the logs and traces.
+	Trusted []string
+
+	// MaxLength is the longest id that is used.  Longer ids are replaced.
+	// The default is 128.
+	MaxLength int
+}
+
+// Propagator accepts or generates the request ids and passes them on.
+type Propagator struct {
+	headers   []string
+	trusted   []netip.Prefix
+	maxLength int
+	logger    *zap.Logger
+}
+
+// New creates the propagator.
+func New(opts ...Option) (*Propagator, error) {
+	var p Propagator
+
+	for _, opt := range opts {
+		if opt != nil {
+			if err := opt.apply(&p); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if len(p.headers) == 0 {
+		p.headers = []string{DefaultHeader, MidtHeader}
+	}
+	if p.maxLength <= 0 {
+		p.maxLength = DefaultMaxLength
+	}
+	if p.logger == nil {
+		p.logger = zap.NewNop()
+	}
+
+	return &p, nil
+}
+
+// Header returns the header the id is sent in.
+func (p *Propagator) Header() string {
+	return p.headers[0]
+}
+
+// Middleware sets the id of the request on the response and in the request
+// context, where Get finds it.  The request-scoped logger gets the
+// http.request.id field, so every line logged for the request carries it.
+func (p *Propagator) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		id := p.incoming(req)
+		if id == "" {
+			id = generate()
+		}
+
+		resp.Header().Set(p.Header(), id)
+
+		ctx := With(req.Context(), id)
+		ctx = sallust.With(ctx, sallust.GetDefault(ctx, p.logger).With(
+			zap.String(log.HTTPRequestID, id),
+		))
+
+		next.ServeHTTP(resp, req.WithContext(ctx))
+	})
+}
+
+// incoming returns the id sent by the caller if it can be used.
+func (p *Propagator) incoming(req *http.Request) string {
+	if !p.trustedCaller(req.RemoteAddr) {
+		return ""
+	}
+
+	for _, h := range p.headers {
+		if id := req.Header.Get(h); id != "" {
+			if p.valid(id) {
+				return id
+			}
+			return ""
+		}
+	}
+
+	return ""
+}
+
+func (p *Propagator) trustedCaller(remoteAddr string) bool {
+	if len(p.trusted) == 0 {
+		return false
+	}
+
+	ap, err := netip.ParseAddrPort(remoteAddr)
+	if err != nil {
+		return false
+	}
+
+	addr := ap.Addr().Unmap()
+	for _, prefix := range p.trusted {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// valid allows the printable ascii characters other than space, so the ids
+// can't inject anything into the headers or logs.
+func (p *Propagator) valid(id string) bool {
+	if len(id) > p.maxLength {
+		return false
+	}
+
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+
+	return true
+}
+
+func generate() string {
+	var buf [16]byte
+	_, _ = rand.Read(buf[:])
+	return hex.EncodeToString(buf[:])
+}
+
+// RoundTripper decorates next so that requests made with a request context
+// carry its id.  Requests that already have the header are sent as is.
+func (p *Propagator) RoundTripper(next http.RoundTripper) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+
+	return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		id := Get(req.Context())
+		if id == "" || req.Header.Get(p.Header()) != "" {
+			return next.RoundTrip(req)
+		}
+
+		req = req.Clone(req.Context())
+		req.Header.Set(p.Header(), id)
+
+		return next.RoundTrip(req)
+	})
+}
+
+// ClientOption returns an arrangehttp option that adds the request ids to
+// clients built from an arrangehttp.ClientConfig.
+func (p *Propagator) ClientOption() arrangehttp.Option[http.Client] {
+	return arrangehttp.ClientMiddleware(p.RoundTripper)
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+type contextKey struct{}
+
+// With returns a context holding the request id.
+func With(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, contextKey{}, id)
+}
+
+// Get returns the request id in the context, or an empty string.
+func Get(ctx context.Context) string {
+	id, _ := ctx.Value(contextKey{}).(string)
+	return id
+}
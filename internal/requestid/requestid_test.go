@@ -0,0 +1,191 @@
+// SPDX-FileCopyrightText: 2024 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package requestid
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xmidt-org/sallust"
+	"github.com/xmidt-org/skeleton/internal/log"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestMiddleware(t *testing.T) {
+	core, logs := observer.New(zap.InfoLevel)
+	p, err := New(
+		WithConfig(Config{
+			Trusted:   []string{"10.0.0.0/8", "::1/128"},
+			MaxLength: 16,
+		}),
+		WithLogger(zap.New(core)),
+	)
+	require.NoError(t, err)
+
+	var got string
+	h := p.Middleware(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		got = Get(r.Context())
+		sallust.Get(r.Context()).Info("handled")
+	}))
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		headers    map[string]string
+		want       string
+	}{
+		{
+			name:       "trusted",
+			remoteAddr: "10.1.2.3:1234",
+			headers:    map[string]string{"X-Request-ID": "abc123"},
+			want:       "abc123",
+		}, {
+			name:       "midt header",
+			remoteAddr: "[::1]:1234",
+			headers:    map[string]string{"X-Midt-Request-Id": "def456"},
+			want:       "def456",
+		}, {
+			name:       "first header wins",
+			remoteAddr: "10.1.2.3:1234",
+			headers:    map[string]string{"X-Request-Id": "abc123", "X-Midt-Request-Id": "def456"},
+			want:       "abc123",
+		}, {
+			name:       "untrusted",
+			remoteAddr: "192.168.1.1:1234",
+			headers:    map[string]string{"X-Request-Id": "abc123"},
+		}, {
+			name:       "too long",
+			remoteAddr: "10.1.2.3:1234",
+			headers:    map[string]string{"X-Request-Id": strings.Repeat("a", 17)},
+		}, {
+			name:       "invalid characters",
+			remoteAddr: "10.1.2.3:1234",
+			headers:    map[string]string{"X-Request-Id": "abc 123"},
+		}, {
+			name:       "missing",
+			remoteAddr: "10.1.2.3:1234",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			logs.TakeAll()
+
+			req := httptest.NewRequest("GET", "/", nil)
+			req.RemoteAddr = tc.remoteAddr
+			for k, v := range tc.headers {
+				req.Header.Set(k, v)
+			}
+			resp := httptest.NewRecorder()
+			h.ServeHTTP(resp, req)
+
+			if tc.want != "" {
+				assert.Equal(t, tc.want, got)
+			} else {
+				assert.Len(t, got, 32)
+				assert.NotEqual(t, tc.headers["X-Request-Id"], got)
+			}
+			assert.Equal(t, got, resp.Header().Get(DefaultHeader))
+
+			entries := logs.FilterMessage("handled").All()
+			require.Len(t, entries, 1)
+			assert.Equal(t, got, entries[0].ContextMap()[log.HTTPRequestID])
+		})
+	}
+}
+
+func TestMiddlewareUntrustedByDefault(t *testing.T) {
+	p, err := New()
+	require.NoError(t, err)
+
+	var got string
+	h := p.Middleware(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		got = Get(r.Context())
+	}))
+
+	for _, addr := range []string{"10.1.2.3:1234", "127.0.0.1:1234"} {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.RemoteAddr = addr
+		req.Header.Set(DefaultHeader, "abc123")
+		h.ServeHTTP(httptest.NewRecorder(), req)
+
+		assert.Len(t, got, 32, addr)
+	}
+}
+
+func TestMiddlewareHeaders(t *testing.T) {
+	p, err := New(WithConfig(Config{
+		Headers: []string{"x-correlation-id"},
+		Trusted: []string{"0.0.0.0/0"},
+	}))
+	require.NoError(t, err)
+	assert.Equal(t, "X-Correlation-Id", p.Header())
+
+	var got string
+	h := p.Middleware(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		got = Get(r.Context())
+	}))
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("X-Correlation-Id", "abc123")
+	req.Header.Set(DefaultHeader, "ignored")
+	resp := httptest.NewRecorder()
+	h.ServeHTTP(resp, req)
+
+	assert.Equal(t, "abc123", got)
+	assert.Equal(t, "abc123", resp.Header().Get("X-Correlation-Id"))
+	assert.Empty(t, resp.Header().Get(DefaultHeader))
+}
+
+func TestRoundTripper(t *testing.T) {
+	var got []string
+	server := httptest.NewServer(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		got = append(got, r.Header.Get(DefaultHeader))
+	}))
+	defer server.Close()
+
+	p, err := New()
+	require.NoError(t, err)
+	client := &http.Client{Transport: p.RoundTripper(nil)}
+
+	send := func(req *http.Request) {
+		resp, err := client.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+	}
+
+	req, err := http.NewRequestWithContext(With(t.Context(), "abc123"), "GET", server.URL, nil)
+	require.NoError(t, err)
+	send(req)
+
+	// An id already on the request is kept.
+	req, err = http.NewRequestWithContext(With(t.Context(), "abc123"), "GET", server.URL, nil)
+	require.NoError(t, err)
+	req.Header.Set(DefaultHeader, "def456")
+	send(req)
+
+	req, err = http.NewRequestWithContext(t.Context(), "GET", server.URL, nil)
+	require.NoError(t, err)
+	send(req)
+
+	assert.Equal(t, []string{"abc123", "def456", ""}, got)
+}
+
+func TestInvalidConfig(t *testing.T) {
+	tests := []Config{
+		{Headers: []string{""}},
+		{Trusted: []string{"10.0.0.1"}},
+		{Trusted: []string{"nonsense"}},
+	}
+
+	for _, c := range tests {
+		_, err := New(WithConfig(c))
+		assert.ErrorIs(t, err, ErrInvalidConfig)
+	}
+}
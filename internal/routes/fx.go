@@ -0,0 +1,46 @@
+// SPDX-FileCopyrightText: 2024 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package routes
+
+import (
+	"net/http"
+
+	"github.com/xmidt-org/skeleton/internal/apiauth"
+	"go.uber.org/fx"
+)
+
+type TableIn struct {
+	fx.In
+	Config  Config
+	ApiAuth *apiauth.Auth
+
+	// Servers are the names of the servers routes can be served on.
+	Servers []string `name:"routes.servers"`
+
+	// Handlers are the handlers provided by other modules.
+	Handlers []Handler `group:"routes.handlers"`
+
+	// Middleware are the middleware provided by other modules.
+	Middleware []Middleware `group:"routes.middleware"`
+
+	// CORS are the CORS policies of the servers.
+	CORS []CORS `group:"routes.cors"`
+}
+
+var Module = fx.Module("routes",
+	fx.Provide(
+		func(in TableIn) (*Table, error) {
+			return New(
+				WithConfig(in.Config),
+				WithServers(in.Servers...),
+				WithHandlers(in.Handlers...),
+				WithMiddleware(in.Middleware...),
+				WithCORS(in.CORS...),
+				WithAuth(func(next http.Handler) http.Handler {
+					return in.ApiAuth.Then(next.ServeHTTP)
+				}),
+			)
+		},
+	),
+)
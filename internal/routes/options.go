@@ -0,0 +1,84 @@
+// SPDX-FileCopyrightText: 2024 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package routes
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/xmidt-org/skeleton/internal/cors"
+)
+
+func WithConfig(c Config) Option {
+	return optionFunc(func(t *Table) error {
+		t.config = c
+		return nil
+	})
+}
+
+// WithServers sets the names of the servers routes can be served on.
+func WithServers(names ...string) Option {
+	return optionFunc(func(t *Table) error {
+		t.servers = append(t.servers, names...)
+		return nil
+	})
+}
+
+// WithHandlers adds handlers routes can refer to.  Each name can only be
+// used once.
+func WithHandlers(handlers ...Handler) Option {
+	return optionFunc(func(t *Table) error {
+		for _, h := range handlers {
+			if _, ok := t.handlers[h.Name]; ok || h.Name == "" || h.Handler == nil {
+				return fmt.Errorf("%w: handler '%s' is empty or already registered", ErrInvalidConfig, h.Name)
+			}
+			t.handlers[h.Name] = h.Handler
+		}
+		return nil
+	})
+}
+
+// WithMiddleware adds middleware routes can refer to.  Each name can only be
+// used once.
+func WithMiddleware(middleware ...Middleware) Option {
+	return optionFunc(func(t *Table) error {
+		for _, mw := range middleware {
+			if _, ok := t.middleware[mw.Name]; ok || mw.Name == "" || mw.Middleware == nil {
+				return fmt.Errorf("%w: middleware '%s' is empty or already registered", ErrInvalidConfig, mw.Name)
+			}
+			t.middleware[mw.Name] = mw.Middleware
+		}
+		return nil
+	})
+}
+
+// WithAuth sets the middleware used for routes that require authentication.
+func WithAuth(auth func(http.Handler) http.Handler) Option {
+	return optionFunc(func(t *Table) error {
+		t.auth = auth
+		return nil
+	})
+}
+
+// WithCORS sets the CORS policies of the servers.  Policies without origins
+// are ignored.
+func WithCORS(policies ...CORS) Option {
+	return optionFunc(func(t *Table) error {
+		for _, c := range policies {
+			if !c.Config.Enabled() {
+				continue
+			}
+			if _, ok := t.cors[c.Server]; ok || c.Server == "" {
+				return fmt.Errorf("%w: cors for server '%s' is empty or already registered", ErrInvalidConfig, c.Server)
+			}
+
+			p, err := cors.New(cors.WithConfig(c.Config))
+			if err != nil {
+				return fmt.Errorf("server '%s': %w", c.Server, err)
+			}
+			t.cors[c.Server] = p
+		}
+		return nil
+	})
+}
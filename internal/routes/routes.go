@@ -0,0 +1,385 @@
+// SPDX-FileCopyrightText: 2024 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package routes
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/xmidt-org/skeleton/internal/cors"
+)
+
+const (
+	// AuthRequired protects the route with the api authentication.  This is
+	// the default.
+	AuthRequired = "required"
+
+	// AuthNone leaves the route open.
+	AuthNone = "none"
+)
+
+var (
+	ErrInvalidConfig = errors.New("invalid route config")
+	ErrUnknownName   = errors.New("unknown name")
+	ErrConflict      = errors.New("conflicting routes")
+)
+
+// Config is the route table, keyed by the route name.
+type Config map[string]Route
+
+// Route describes where and how a handler is served.
+type Route struct {
+	// Handler is the name of the handler that serves the route.  Defaults to
+	// the route name.
+	Handler string
+
+	// Path is the chi route pattern, for example /api/{version}/ok.
+	Path string
+
+	// Methods are the http methods served.  Defaults to GET.  HEAD is served
+	// for GET routes and OPTIONS is answered for every route unless they are
+	// listed.  Other methods get a 405 response.  Both include an Allow
+	// header.
+	Methods []string
+
+	// Servers are the names of the servers the route is served on.
+	Servers []string
+
+	// Auth is the authentication policy, either "required" (the default) or
+	// "none".
+	Auth string
+
+	// Middleware are the names of the middleware applied to the route, in
+	// order.  The first is the outermost and runs before authentication.
+	Middleware []string
+
+	// CORS replaces the CORS policy of the servers for the route.  A policy
+	// without origins turns CORS off for the route.
+	CORS *cors.Config
+}
+
+// Handler is a named http.Handler that routes can refer to.  Handlers are
+// contributed through the "routes.handlers" fx value group.
+type Handler struct {
+	Name    string
+	Handler http.Handler
+}
+
+// Middleware is a named middleware that routes can refer to.  Middleware is
+// contributed through the "routes.middleware" fx value group.
+type Middleware struct {
+	Name       string
+	Middleware func(http.Handler) http.Handler
+}
+
+// CORS is the CORS policy of the routes of a server.  Policies are
+// contributed through the "routes.cors" fx value group.
+type CORS struct {
+	Server string
+	Config cors.Config
+}
+
+// Table holds the routes ready to be served.
+type Table struct {
+	config     Config
+	servers    []string
+	handlers   map[string]http.Handler
+	middleware map[string]func(http.Handler) http.Handler
+	auth       func(http.Handler) http.Handler
+	cors       map[string]*cors.Policy
+
+	// paths are the handlers by server and normalized path.
+	paths map[string]map[string]*pathHandler
+}
+
+type route struct {
+	path    string
+	methods []string
+	servers []string
+	handler http.Handler
+
+	// cors is the policy of the route when it replaces the servers' one.
+	cors    *cors.Policy
+	ownCORS bool
+}
+
+// pathHandler serves all the methods of a path on a server.
+type pathHandler struct {
+	path    string
+	route   string
+	methods map[string]http.Handler
+	owners  map[string]string
+	cors    map[string]*cors.Policy
+	allow   string
+}
+
+// ServeHTTP answers CORS preflight requests with the policy of the requested
+// method before anything else, so they don't need to be authenticated.
+func (ph *pathHandler) ServeHTTP(resp http.ResponseWriter, req *http.Request) {
+	if cors.IsPreflight(req) {
+		if p := ph.policy(req.Header.Get("Access-Control-Request-Method")); p != nil {
+			p.Preflight(resp, req)
+			return
+		}
+	} else if p := ph.policy(req.Method); p != nil {
+		p.Apply(resp, req)
+	}
+
+	if h, ok := ph.methods[req.Method]; ok {
+		h.ServeHTTP(resp, req)
+		return
+	}
+
+	if req.Method == http.MethodHead {
+		if h, ok := ph.methods[http.MethodGet]; ok {
+			h.ServeHTTP(resp, req)
+			return
+		}
+	}
+
+	resp.Header().Set("Allow", ph.allow)
+	if req.Method == http.MethodOptions {
+		resp.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	resp.WriteHeader(http.StatusMethodNotAllowed)
+}
+
+// policy returns the CORS policy of the method, if there is one.
+func (ph *pathHandler) policy(method string) *cors.Policy {
+	if p, ok := ph.cors[method]; ok {
+		return p
+	}
+	if method == http.MethodHead {
+		return ph.cors[http.MethodGet]
+	}
+	return nil
+}
+
+// setAllow computes the Allow header from the methods.
+func (ph *pathHandler) setAllow() {
+	allow := make([]string, 0, len(ph.methods)+2)
+	for method := range ph.methods {
+		allow = append(allow, method)
+	}
+	if _, ok := ph.methods[http.MethodGet]; ok && !slices.Contains(allow, http.MethodHead) {
+		allow = append(allow, http.MethodHead)
+	}
+	if !slices.Contains(allow, http.MethodOptions) {
+		allow = append(allow, http.MethodOptions)
+	}
+	slices.Sort(allow)
+
+	ph.allow = strings.Join(allow, ", ")
+}
+
+type Option interface {
+	apply(*Table) error
+}
+
+type optionFunc func(*Table) error
+
+func (f optionFunc) apply(t *Table) error {
+	return f(t)
+}
+
+// New creates the route table.  Routes that refer to unknown servers,
+// handlers or middleware and routes that conflict are reported as errors.
+func New(opts ...Option) (*Table, error) {
+	t := Table{
+		handlers:   make(map[string]http.Handler),
+		middleware: make(map[string]func(http.Handler) http.Handler),
+		cors:       make(map[string]*cors.Policy),
+		paths:      make(map[string]map[string]*pathHandler),
+	}
+
+	for _, opt := range opts {
+		if opt != nil {
+			if err := opt.apply(&t); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if err := t.build(); err != nil {
+		return nil, err
+	}
+
+	return &t, nil
+}
+
+// Handler returns the handler that serves the routes of the server.
+func (t *Table) Handler(server string) http.Handler {
+	paths := t.paths[server]
+	keys := make([]string, 0, len(paths))
+	for key := range paths {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+
+	mux := chi.NewMux()
+	for _, key := range keys {
+		mux.Handle(paths[key].path, paths[key])
+	}
+
+	return mux
+}
+
+var paramPattern = regexp.MustCompile(`\{[^}]*\}`)
+
+func (t *Table) build() error {
+	names := make([]string, 0, len(t.config))
+	for name := range t.config {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	var errs []error
+	for _, name := range names {
+		r, err := t.route(name, t.config[name])
+		if err != nil {
+			errs = append(errs, fmt.Errorf("route '%s': %w", name, err))
+			continue
+		}
+
+		for _, server := range r.servers {
+			if err := t.add(server, name, r); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+
+	for _, paths := range t.paths {
+		for _, ph := range paths {
+			ph.setAllow()
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// add adds the route to the server, checking for conflicts with the routes
+// already added.
+func (t *Table) add(server, name string, r route) error {
+	if t.paths[server] == nil {
+		t.paths[server] = make(map[string]*pathHandler)
+	}
+
+	key := paramPattern.ReplaceAllString(r.path, "{}")
+	ph, ok := t.paths[server][key]
+	if !ok {
+		ph = &pathHandler{
+			path:    r.path,
+			route:   name,
+			methods: make(map[string]http.Handler),
+			owners:  make(map[string]string),
+			cors:    make(map[string]*cors.Policy),
+		}
+		t.paths[server][key] = ph
+	}
+
+	if ph.path != r.path {
+		return fmt.Errorf("%w: routes '%s' and '%s' use different parameter names for %s on %s",
+			ErrConflict, ph.route, name, r.path, server)
+	}
+
+	for _, method := range r.methods {
+		if other, ok := ph.owners[method]; ok {
+			return fmt.Errorf("%w: routes '%s' and '%s' both serve %s %s on %s",
+				ErrConflict, other, name, method, r.path, server)
+		}
+		ph.owners[method] = name
+		ph.methods[method] = r.handler
+
+		p := t.cors[server]
+		if r.ownCORS {
+			p = r.cors
+		}
+		if p != nil {
+			ph.cors[method] = p
+		}
+	}
+
+	return nil
+}
+
+// route validates the route and builds its handler.
+func (t *Table) route(name string, cfg Route) (route, error) {
+	r := route{
+		path: cfg.Path,
+	}
+
+	if !strings.HasPrefix(r.path, "/") {
+		return route{}, fmt.Errorf("%w: the path must start with '/'", ErrInvalidConfig)
+	}
+
+	for _, method := range cfg.Methods {
+		method = strings.ToUpper(method)
+		if !slices.Contains(r.methods, method) {
+			r.methods = append(r.methods, method)
+		}
+	}
+	if len(r.methods) == 0 {
+		r.methods = []string{http.MethodGet}
+	}
+
+	if len(cfg.Servers) == 0 {
+		return route{}, fmt.Errorf("%w: at least one server is required", ErrInvalidConfig)
+	}
+	for _, server := range cfg.Servers {
+		if !slices.Contains(t.servers, server) {
+			return route{}, fmt.Errorf("%w: server '%s'", ErrUnknownName, server)
+		}
+		if !slices.Contains(r.servers, server) {
+			r.servers = append(r.servers, server)
+		}
+	}
+
+	handlerName := cfg.Handler
+	if handlerName == "" {
+		handlerName = name
+	}
+	h, ok := t.handlers[handlerName]
+	if !ok {
+		return route{}, fmt.Errorf("%w: handler '%s'", ErrUnknownName, handlerName)
+	}
+
+	switch cfg.Auth {
+	case "", AuthRequired:
+		if t.auth != nil {
+			h = t.auth(h)
+		}
+	case AuthNone:
+	default:
+		return route{}, fmt.Errorf("%w: unknown auth policy '%s'", ErrInvalidConfig, cfg.Auth)
+	}
+
+	for i := len(cfg.Middleware) - 1; i >= 0; i-- {
+		mw, ok := t.middleware[cfg.Middleware[i]]
+		if !ok {
+			return route{}, fmt.Errorf("%w: middleware '%s'", ErrUnknownName, cfg.Middleware[i])
+		}
+		h = mw(h)
+	}
+
+	if cfg.CORS != nil {
+		r.ownCORS = true
+		if cfg.CORS.Enabled() {
+			p, err := cors.New(cors.WithConfig(*cfg.CORS))
+			if err != nil {
+				return route{}, err
+			}
+			r.cors = p
+		}
+	}
+
+	r.handler = h
+	return r, nil
+}
@@ -0,0 +1,323 @@
+// SPDX-FileCopyrightText: 2024 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package routes
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xmidt-org/skeleton/internal/cors"
+)
+
+// text returns a handler that writes the text.
+func text(s string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = io.WriteString(w, s)
+	})
+}
+
+// tag returns middleware that adds the name to the X-Trail header.
+func tag(name string) Middleware {
+	return Middleware{
+		Name: name,
+		Middleware: func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Add("X-Trail", name)
+				next.ServeHTTP(w, r)
+			})
+		},
+	}
+}
+
+// denyAll rejects every request.
+func denyAll(http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Add("X-Trail", "auth")
+		w.WriteHeader(http.StatusUnauthorized)
+	})
+}
+
+func newTable(cfg Config) (*Table, error) {
+	return New(
+		WithConfig(cfg),
+		WithServers("primary", "alternate"),
+		WithHandlers(
+			Handler{Name: "ok", Handler: text("ok")},
+			Handler{Name: "other", Handler: text("other")},
+		),
+		WithMiddleware(tag("first"), tag("second")),
+		WithAuth(denyAll),
+	)
+}
+
+func serve(h http.Handler, method, path string) *httptest.ResponseRecorder {
+	resp := httptest.NewRecorder()
+	h.ServeHTTP(resp, httptest.NewRequest(method, path, nil))
+	return resp
+}
+
+func TestTable(t *testing.T) {
+	table, err := newTable(Config{
+		"ok": {
+			Path:    "/api/{version}/ok",
+			Servers: []string{"primary"},
+			Auth:    AuthNone,
+		},
+		"renamed": {
+			Handler: "other",
+			Path:    "/other",
+			Methods: []string{"post"},
+			Servers: []string{"alternate"},
+			Auth:    AuthNone,
+		},
+		"protected": {
+			Handler:    "ok",
+			Path:       "/protected",
+			Servers:    []string{"primary"},
+			Middleware: []string{"first", "second"},
+		},
+	})
+	require.NoError(t, err)
+
+	primary := table.Handler("primary")
+	alternate := table.Handler("alternate")
+
+	resp := serve(primary, "GET", "/api/v1/ok")
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, "ok", resp.Body.String())
+
+	resp = serve(alternate, "POST", "/other")
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, "other", resp.Body.String())
+
+	// Routes are only served on their server.
+	assert.Equal(t, http.StatusNotFound, serve(alternate, "GET", "/api/v1/ok").Code)
+	assert.Equal(t, http.StatusNotFound, serve(primary, "POST", "/other").Code)
+
+	// The middleware runs in order, before authentication.
+	resp = serve(primary, "GET", "/protected")
+	assert.Equal(t, http.StatusUnauthorized, resp.Code)
+	assert.Equal(t, []string{"first", "second", "auth"}, resp.Header().Values("X-Trail"))
+}
+
+func TestTableMethods(t *testing.T) {
+	table, err := newTable(Config{
+		"ok": {
+			Path:    "/ok",
+			Servers: []string{"primary", "alternate"},
+			Auth:    AuthNone,
+		},
+		"other": {
+			Path:    "/ok",
+			Methods: []string{"put", "DELETE"},
+			Servers: []string{"primary"},
+		},
+	})
+	require.NoError(t, err)
+
+	for _, server := range []string{"primary", "alternate"} {
+		resp := serve(table.Handler(server), "GET", "/ok")
+		assert.Equal(t, http.StatusOK, resp.Code, server)
+		assert.Equal(t, "ok", resp.Body.String(), server)
+
+		resp = serve(table.Handler(server), "HEAD", "/ok")
+		assert.Equal(t, http.StatusOK, resp.Code, server)
+	}
+
+	tests := []struct {
+		server string
+		method string
+		code   int
+		allow  string
+	}{
+		{server: "primary", method: "OPTIONS", code: http.StatusNoContent, allow: "DELETE, GET, HEAD, OPTIONS, PUT"},
+		{server: "primary", method: "POST", code: http.StatusMethodNotAllowed, allow: "DELETE, GET, HEAD, OPTIONS, PUT"},
+		{server: "primary", method: "PUT", code: http.StatusUnauthorized},
+		{server: "alternate", method: "OPTIONS", code: http.StatusNoContent, allow: "GET, HEAD, OPTIONS"},
+		{server: "alternate", method: "PUT", code: http.StatusMethodNotAllowed, allow: "GET, HEAD, OPTIONS"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.server+" "+tc.method, func(t *testing.T) {
+			resp := serve(table.Handler(tc.server), tc.method, "/ok")
+			assert.Equal(t, tc.code, resp.Code)
+			assert.Equal(t, tc.allow, resp.Header().Get("Allow"))
+		})
+	}
+}
+
+func TestTableInvalid(t *testing.T) {
+	tests := []struct {
+		name   string
+		config Config
+		err    error
+	}{
+		{
+			name:   "unknown handler",
+			config: Config{"missing": {Path: "/missing", Servers: []string{"primary"}}},
+			err:    ErrUnknownName,
+		}, {
+			name:   "unknown server",
+			config: Config{"ok": {Path: "/ok", Servers: []string{"other"}}},
+			err:    ErrUnknownName,
+		}, {
+			name:   "unknown middleware",
+			config: Config{"ok": {Path: "/ok", Servers: []string{"primary"}, Middleware: []string{"missing"}}},
+			err:    ErrUnknownName,
+		}, {
+			name:   "unknown auth policy",
+			config: Config{"ok": {Path: "/ok", Servers: []string{"primary"}, Auth: "sometimes"}},
+			err:    ErrInvalidConfig,
+		}, {
+			name:   "no server",
+			config: Config{"ok": {Path: "/ok"}},
+			err:    ErrInvalidConfig,
+		}, {
+			name:   "relative path",
+			config: Config{"ok": {Path: "ok", Servers: []string{"primary"}}},
+			err:    ErrInvalidConfig,
+		}, {
+			name: "same path",
+			config: Config{
+				"ok":    {Path: "/ok", Servers: []string{"primary"}},
+				"other": {Path: "/ok", Servers: []string{"primary"}},
+			},
+			err: ErrConflict,
+		}, {
+			name: "same pattern",
+			config: Config{
+				"ok":    {Path: "/api/{version}/ok", Servers: []string{"primary"}},
+				"other": {Path: "/api/{v}/ok", Methods: []string{"GET"}, Servers: []string{"primary"}},
+			},
+			err: ErrConflict,
+		}, {
+			name: "different parameter names",
+			config: Config{
+				"ok":    {Path: "/api/{version}/ok", Servers: []string{"primary"}},
+				"other": {Path: "/api/{v}/ok", Methods: []string{"POST"}, Servers: []string{"primary"}},
+			},
+			err: ErrConflict,
+		}, {
+			name:   "invalid cors",
+			config: Config{"ok": {Path: "/ok", Servers: []string{"primary"}, CORS: &cors.Config{OriginPatterns: []string{"("}}}},
+			err:    cors.ErrInvalidConfig,
+		}, {
+			name: "one of several servers",
+			config: Config{
+				"ok":    {Path: "/ok", Servers: []string{"primary", "alternate"}},
+				"other": {Path: "/ok", Servers: []string{"alternate"}},
+			},
+			err: ErrConflict,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := newTable(tc.config)
+			assert.ErrorIs(t, err, tc.err)
+		})
+	}
+}
+
+func TestTableCORS(t *testing.T) {
+	table, err := New(
+		WithConfig(Config{
+			"ok": {
+				Path:    "/ok",
+				Methods: []string{"GET", "PUT"},
+				Servers: []string{"primary", "alternate"},
+			},
+			"open": {
+				Handler: "ok",
+				Path:    "/open",
+				Servers: []string{"alternate"},
+				CORS:    &cors.Config{Origins: []string{"*"}},
+			},
+			"closed": {
+				Handler: "ok",
+				Path:    "/closed",
+				Servers: []string{"alternate"},
+				CORS:    &cors.Config{},
+			},
+		}),
+		WithServers("primary", "alternate"),
+		WithHandlers(Handler{Name: "ok", Handler: text("ok")}),
+		WithAuth(denyAll),
+		WithCORS(
+			CORS{Server: "alternate", Config: cors.Config{
+				Origins:     []string{"https://tools.example.com"},
+				Methods:     []string{"GET", "PUT"},
+				Credentials: true,
+			}},
+			CORS{Server: "primary"},
+		),
+	)
+	require.NoError(t, err)
+
+	request := func(server, method, path, acrm string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		req.Header.Set("Origin", "https://tools.example.com")
+		if acrm != "" {
+			req.Header.Set("Access-Control-Request-Method", acrm)
+		}
+		resp := httptest.NewRecorder()
+		table.Handler(server).ServeHTTP(resp, req)
+		return resp
+	}
+
+	// The preflight is answered before authentication.
+	resp := request("alternate", "OPTIONS", "/ok", "PUT")
+	assert.Equal(t, http.StatusNoContent, resp.Code)
+	assert.Equal(t, "https://tools.example.com", resp.Header().Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "GET, PUT", resp.Header().Get("Access-Control-Allow-Methods"))
+	assert.Equal(t, "true", resp.Header().Get("Access-Control-Allow-Credentials"))
+	assert.Empty(t, resp.Header().Values("X-Trail"))
+
+	// Actual requests get the headers and are authenticated.
+	resp = request("alternate", "GET", "/ok", "")
+	assert.Equal(t, http.StatusUnauthorized, resp.Code)
+	assert.Equal(t, "https://tools.example.com", resp.Header().Get("Access-Control-Allow-Origin"))
+	resp = request("alternate", "HEAD", "/ok", "")
+	assert.Equal(t, "https://tools.example.com", resp.Header().Get("Access-Control-Allow-Origin"))
+
+	// Routes replace the policy of the server.
+	resp = request("alternate", "OPTIONS", "/open", "GET")
+	assert.Equal(t, http.StatusNoContent, resp.Code)
+	assert.Equal(t, "*", resp.Header().Get("Access-Control-Allow-Origin"))
+
+	resp = request("alternate", "OPTIONS", "/closed", "GET")
+	assert.Equal(t, http.StatusNoContent, resp.Code)
+	assert.Empty(t, resp.Header().Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "GET, HEAD, OPTIONS", resp.Header().Get("Allow"))
+
+	// A server without a policy answers OPTIONS as before.
+	resp = request("primary", "OPTIONS", "/ok", "PUT")
+	assert.Equal(t, http.StatusNoContent, resp.Code)
+	assert.Empty(t, resp.Header().Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "GET, HEAD, OPTIONS, PUT", resp.Header().Get("Allow"))
+}
+
+func TestTableNoConflict(t *testing.T) {
+	_, err := newTable(Config{
+		"ok":        {Path: "/ok", Servers: []string{"primary"}},
+		"alternate": {Handler: "ok", Path: "/ok", Servers: []string{"alternate"}},
+		"post":      {Handler: "other", Path: "/ok", Methods: []string{"POST"}, Servers: []string{"primary"}},
+	})
+	assert.NoError(t, err)
+}
+
+func TestDuplicateRegistration(t *testing.T) {
+	_, err := New(WithHandlers(
+		Handler{Name: "ok", Handler: text("ok")},
+		Handler{Name: "ok", Handler: text("ok")},
+	))
+	assert.ErrorIs(t, err, ErrInvalidConfig)
+
+	_, err = New(WithMiddleware(tag("first"), tag("first")))
+	assert.ErrorIs(t, err, ErrInvalidConfig)
+}
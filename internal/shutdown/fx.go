@@ -0,0 +1,58 @@
+// SPDX-FileCopyrightText: 2024 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package shutdown
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/xmidt-org/skeleton/internal/health"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+)
+
+type DrainerIn struct {
+	fx.In
+	Config Config `optional:"true"`
+	Logger *zap.Logger
+	Health *health.Registry
+}
+
+// Module provides the *Drainer.  Use Hook to stop the servers with it.
+var Module = fx.Module("shutdown",
+	fx.Provide(
+		func(in DrainerIn) (*Drainer, error) {
+			return New(
+				WithConfig(in.Config),
+				WithLogger(in.Logger),
+				WithReadiness(in.Health),
+			)
+		},
+	),
+)
+
+// Hook stops the tracked servers with the drainer when the application
+// stops.  Hooks stop in reverse order and fx runs the invokes of modules
+// before its own, so Hook must be used at the top level after the servers
+// are provided for the drain to happen before they are stopped.
+//
+// stopTimeout is set as the fx stop timeout, which bounds the whole
+// shutdown.  A drain and timeout that don't fit in it, with StopMargin left
+// for the other stop hooks, fail the start instead of being cut short.
+func Hook(stopTimeout time.Duration) fx.Option {
+	return fx.Options(
+		fx.StopTimeout(stopTimeout),
+		fx.Invoke(
+			func(lc fx.Lifecycle, d *Drainer) error {
+				if need := d.Duration() + StopMargin; need > stopTimeout {
+					return fmt.Errorf("%w: the drain and timeout need %s of the %s stop timeout",
+						ErrInvalidConfig, need, stopTimeout)
+				}
+
+				lc.Append(fx.StopHook(d.Stop))
+				return nil
+			},
+		),
+	)
+}
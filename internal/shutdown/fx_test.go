@@ -0,0 +1,37 @@
+// SPDX-FileCopyrightText: 2024 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package shutdown
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/fx"
+)
+
+func TestHook(t *testing.T) {
+	tests := []struct {
+		name   string
+		config Config
+		err    error
+	}{
+		{name: "default", config: Config{}},
+		{name: "fits", config: Config{Drain: 20 * time.Second, Timeout: 30 * time.Second}},
+		{name: "too long", config: Config{Drain: 30 * time.Second, Timeout: 30 * time.Second}, err: ErrInvalidConfig},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			app := fx.New(
+				fx.NopLogger,
+				fx.Provide(func() (*Drainer, error) {
+					return New(WithConfig(tc.config))
+				}),
+				Hook(time.Minute),
+			)
+			assert.ErrorIs(t, app.Err(), tc.err)
+		})
+	}
+}
@@ -0,0 +1,41 @@
+// SPDX-FileCopyrightText: 2024 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package shutdown
+
+import "go.uber.org/zap"
+
+type Option interface {
+	apply(*Drainer) error
+}
+
+type optionFunc func(*Drainer) error
+
+func (f optionFunc) apply(d *Drainer) error {
+	return f(d)
+}
+
+func WithConfig(c Config) Option {
+	return optionFunc(func(d *Drainer) error {
+		d.config = c
+		return nil
+	})
+}
+
+// WithLogger sets the logger the shutdown phases are logged to.
+func WithLogger(logger *zap.Logger) Option {
+	return optionFunc(func(d *Drainer) error {
+		if logger != nil {
+			d.logger = logger
+		}
+		return nil
+	})
+}
+
+// WithReadiness sets what is told to fail readiness when the drain starts.
+func WithReadiness(r Drainable) Option {
+	return optionFunc(func(d *Drainer) error {
+		d.readiness = r
+		return nil
+	})
+}
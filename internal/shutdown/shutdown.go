@@ -0,0 +1,183 @@
+// SPDX-FileCopyrightText: 2024 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+// Package shutdown drains the servers before they stop.  Readiness starts
+// failing first so load balancers stop sending traffic, the servers keep
+// serving for the drain period and are then shut down with a bounded timeout.
+package shutdown
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// StopMargin is the part of the stop timeout left for the stop hooks that
+// run after the servers are stopped.
+const StopMargin = 5 * time.Second
+
+// ErrInvalidConfig is returned when the shutdown doesn't fit in the stop
+// timeout.
+var ErrInvalidConfig = errors.New("invalid shutdown config")
+
+// Config is the configuration for the shutdown.
+type Config struct {
+	// Drain is how long the servers keep serving after readiness starts
+	// failing.  Zero stops the servers right away.
+	Drain time.Duration
+
+	// Timeout is how long the servers have to finish the requests in flight
+	// before their connections are closed.  Defaults to 10s.
+	Timeout time.Duration
+}
+
+// Drainable is the part of the health registry the drainer needs.
+type Drainable interface {
+	Drain()
+}
+
+// Drainer tracks the servers and the requests in flight on them, and stops
+// the servers gracefully.
+type Drainer struct {
+	config    Config
+	logger    *zap.Logger
+	readiness Drainable
+
+	m       sync.Mutex
+	servers []*server
+}
+
+type server struct {
+	name     string
+	server   *http.Server
+	inFlight atomic.Int64
+}
+
+// New creates the drainer.
+func New(opts ...Option) (*Drainer, error) {
+	d := Drainer{
+		logger: zap.NewNop(),
+	}
+
+	for _, opt := range opts {
+		if opt != nil {
+			if err := opt.apply(&d); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if d.config.Timeout <= 0 {
+		d.config.Timeout = 10 * time.Second
+	}
+
+	return &d, nil
+}
+
+// Track adds the server to the ones stopped by the drainer and returns the
+// handler wrapped so the requests in flight are counted.
+func (d *Drainer) Track(name string, s *http.Server, next http.Handler) http.Handler {
+	srv := server{
+		name:   name,
+		server: s,
+	}
+
+	d.m.Lock()
+	d.servers = append(d.servers, &srv)
+	d.m.Unlock()
+
+	return http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		srv.inFlight.Add(1)
+		defer srv.inFlight.Add(-1)
+
+		next.ServeHTTP(resp, req)
+	})
+}
+
+// InFlight returns the number of requests in flight on the named server.
+func (d *Drainer) InFlight(name string) int64 {
+	d.m.Lock()
+	defer d.m.Unlock()
+
+	var n int64
+	for _, srv := range d.servers {
+		if srv.name == name {
+			n += srv.inFlight.Load()
+		}
+	}
+	return n
+}
+
+// Duration is the longest the stop can take: the drain and the timeout.
+func (d *Drainer) Duration() time.Duration {
+	return d.config.Drain + d.config.Timeout
+}
+
+// Stop fails readiness, waits for the drain period and then shuts the
+// servers down.  Servers that do not finish in time have their connections
+// closed.
+func (d *Drainer) Stop(ctx context.Context) error {
+	d.m.Lock()
+	servers := d.servers
+	d.m.Unlock()
+
+	if d.readiness != nil {
+		d.readiness.Drain()
+	}
+
+	if d.config.Drain > 0 {
+		d.logger.Info("shutdown: draining", zap.Duration("drain", d.config.Drain))
+
+		t := time.NewTimer(d.config.Drain)
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			t.Stop()
+			d.logger.Warn("shutdown: drain cut short", zap.Error(ctx.Err()))
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, d.config.Timeout)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	errs := make([]error, len(servers))
+	for i, srv := range servers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = d.stop(ctx, srv)
+		}()
+	}
+	wg.Wait()
+
+	err := errors.Join(errs...)
+	d.logger.Info("shutdown: servers stopped", zap.Error(err))
+
+	return err
+}
+
+func (d *Drainer) stop(ctx context.Context, srv *server) error {
+	logger := d.logger.With(zap.String("server", srv.name))
+	logger.Info("shutdown: stopping server",
+		zap.Int64("in_flight", srv.inFlight.Load()),
+		zap.Duration("timeout", d.config.Timeout),
+	)
+
+	err := srv.server.Shutdown(ctx)
+	if err == nil {
+		return nil
+	}
+
+	logger.Warn("shutdown: closing connections",
+		zap.Int64("in_flight", srv.inFlight.Load()),
+		zap.Error(err),
+	)
+
+	return errors.Join(err, srv.server.Close())
+}
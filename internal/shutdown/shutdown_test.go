@@ -0,0 +1,128 @@
+// SPDX-FileCopyrightText: 2024 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package shutdown
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+type readiness struct {
+	drained atomic.Bool
+}
+
+func (r *readiness) Drain() {
+	r.drained.Store(true)
+}
+
+// start serves the handler tracked by the drainer and returns its url.
+func start(t *testing.T, d *Drainer, h http.Handler) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	s := &http.Server{} // nolint: gosec
+	s.Handler = d.Track("primary", s, h)
+	go func() {
+		_ = s.Serve(l)
+	}()
+
+	return "http://" + l.Addr().String()
+}
+
+func TestDrain(t *testing.T) {
+	core, logs := observer.New(zap.InfoLevel)
+	r := &readiness{}
+
+	d, err := New(
+		WithConfig(Config{Drain: 100 * time.Millisecond, Timeout: time.Second}),
+		WithLogger(zap.New(core)),
+		WithReadiness(r),
+	)
+	require.NoError(t, err)
+
+	release := make(chan struct{})
+	entered := make(chan struct{}, 1)
+	url := start(t, d, http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path == "/slow" {
+			entered <- struct{}{}
+			<-release
+		}
+		_, _ = io.WriteString(w, "ok")
+	}))
+
+	slow := make(chan error, 1)
+	go func() {
+		resp, err := http.Get(url + "/slow") // nolint: noctx
+		if err == nil {
+			resp.Body.Close()
+		}
+		slow <- err
+	}()
+	<-entered
+	assert.Equal(t, int64(1), d.InFlight("primary"))
+
+	stopped := make(chan error, 1)
+	go func() {
+		stopped <- d.Stop(context.Background())
+	}()
+
+	// Readiness fails right away but requests are still served while
+	// draining.
+	require.Eventually(t, r.drained.Load, time.Second, time.Millisecond)
+	resp, err := http.Get(url + "/fast") // nolint: noctx
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	close(release)
+	require.NoError(t, <-slow)
+	require.NoError(t, <-stopped)
+	assert.Equal(t, int64(0), d.InFlight("primary"))
+
+	assert.Equal(t, 1, logs.FilterMessage("shutdown: draining").Len())
+	assert.Equal(t, 1, logs.FilterMessage("shutdown: stopping server").Len())
+	assert.Equal(t, 1, logs.FilterMessage("shutdown: servers stopped").Len())
+}
+
+func TestShutdownTimeout(t *testing.T) {
+	core, logs := observer.New(zap.InfoLevel)
+	d, err := New(
+		WithConfig(Config{Timeout: 50 * time.Millisecond}),
+		WithLogger(zap.New(core)),
+	)
+	require.NoError(t, err)
+
+	release := make(chan struct{})
+	defer close(release)
+	entered := make(chan struct{}, 1)
+	url := start(t, d, http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		entered <- struct{}{}
+		<-release
+	}))
+
+	go func() {
+		resp, err := http.Get(url) // nolint: noctx
+		if err == nil {
+			resp.Body.Close()
+		}
+	}()
+	<-entered
+
+	err = d.Stop(context.Background())
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	entries := logs.FilterMessage("shutdown: closing connections").All()
+	require.Len(t, entries, 1)
+	assert.Equal(t, int64(1), entries[0].ContextMap()["in_flight"])
+}
@@ -0,0 +1,29 @@
+// SPDX-FileCopyrightText: 2024 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package tracing
+
+import (
+	"github.com/xmidt-org/candlelight"
+	"go.opentelemetry.io/otel/propagation"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+)
+
+// Module provides the *Tracing using the candlelight tracer provider.  The
+// candlelight propagator is combined with the W3C baggage propagator, and the
+// request-scoped loggers are derived from the application logger.
+var Module = fx.Module("tracing",
+	fx.Provide(
+		func(c candlelight.Tracing, logger *zap.Logger) (*Tracing, error) {
+			return New(
+				WithLogger(logger),
+				WithTracerProvider(c.TracerProvider()),
+				WithPropagator(propagation.NewCompositeTextMapPropagator(
+					c.Propagator(),
+					propagation.Baggage{},
+				)),
+			)
+		},
+	),
+)
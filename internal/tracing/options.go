@@ -0,0 +1,46 @@
+// SPDX-FileCopyrightText: 2024 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package tracing
+
+import (
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+type Option interface {
+	apply(*Tracing) error
+}
+
+type optionFunc func(*Tracing) error
+
+func (f optionFunc) apply(t *Tracing) error {
+	return f(t)
+}
+
+// WithTracerProvider sets the provider of the tracer the spans are created
+// with.
+func WithTracerProvider(tp trace.TracerProvider) Option {
+	return optionFunc(func(t *Tracing) error {
+		t.provider = tp
+		return nil
+	})
+}
+
+// WithLogger sets the logger the request-scoped loggers are derived from.
+func WithLogger(logger *zap.Logger) Option {
+	return optionFunc(func(t *Tracing) error {
+		t.logger = logger
+		return nil
+	})
+}
+
+// WithPropagator sets the propagator used to extract the caller's trace
+// context from the request headers.
+func WithPropagator(p propagation.TextMapPropagator) Option {
+	return optionFunc(func(t *Tracing) error {
+		t.propagator = p
+		return nil
+	})
+}
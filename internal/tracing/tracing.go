@@ -0,0 +1,131 @@
+// SPDX-FileCopyrightText: 2024 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+// Package tracing starts a server span for every request the http servers
+// handle.  The W3C traceparent and baggage headers are extracted so the span
+// joins the caller's trace, and the span is named after the route pattern
+// that served the request.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/xmidt-org/sallust"
+	"github.com/xmidt-org/skeleton/internal/log"
+	"github.com/xmidt-org/skeleton/internal/middleware"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+	"go.uber.org/zap"
+)
+
+// Name is the instrumentation name of the tracer.
+const Name = "github.com/xmidt-org/skeleton/internal/tracing"
+
+// ServerKey is the span attribute holding the name of the server.
+const ServerKey = attribute.Key("server.name")
+
+// Tracing creates the server spans.
+type Tracing struct {
+	provider   trace.TracerProvider
+	propagator propagation.TextMapPropagator
+	tracer     trace.Tracer
+	logger     *zap.Logger
+}
+
+// New creates the tracing.  Without a tracer provider the spans are not
+// recorded, and without a propagator the W3C trace context and baggage
+// headers are used.
+func New(opts ...Option) (*Tracing, error) {
+	var t Tracing
+
+	for _, opt := range opts {
+		if opt != nil {
+			if err := opt.apply(&t); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if t.logger == nil {
+		t.logger = zap.NewNop()
+	}
+	if t.provider == nil {
+		t.provider = noop.NewTracerProvider()
+	}
+	if t.propagator == nil {
+		t.propagator = propagation.NewCompositeTextMapPropagator(
+			propagation.TraceContext{},
+			propagation.Baggage{},
+		)
+	}
+
+	t.tracer = t.provider.Tracer(Name)
+
+	return &t, nil
+}
+
+// Propagator returns the propagator used for the incoming headers, so the
+// same one can be used for outgoing requests.
+func (t *Tracing) Propagator() propagation.TextMapPropagator {
+	return t.propagator
+}
+
+// Middleware starts a server span for each request to the named server.  The
+// span is named "<method> <route pattern>" once the route is known, and 5xx
+// responses mark it as an error.  The request context gets a logger with the
+// trace.id and span.id of the span, which handlers get with sallust.Get.
+func (t *Tracing) Middleware(server string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+			ctx := t.propagator.Extract(req.Context(), propagation.HeaderCarrier(req.Header))
+
+			ctx, span := t.tracer.Start(ctx, req.Method,
+				trace.WithSpanKind(trace.SpanKindServer),
+				trace.WithAttributes(
+					ServerKey.String(server),
+					semconv.HTTPRequestMethodKey.String(req.Method),
+					semconv.URLPath(req.URL.Path),
+					semconv.UserAgentOriginal(req.UserAgent()),
+					semconv.ClientAddress(req.RemoteAddr),
+				),
+			)
+			defer span.End()
+
+			ctx = sallust.With(ctx, t.logger.With(Fields(ctx)...))
+
+			req, route := middleware.TrackRoute(req.WithContext(ctx))
+			rec := middleware.NewRecorder(resp)
+			next.ServeHTTP(rec, req)
+
+			if pattern := route.Pattern(); pattern != "" {
+				span.SetName(req.Method + " " + pattern)
+				span.SetAttributes(semconv.HTTPRoute(pattern))
+			}
+
+			span.SetAttributes(semconv.HTTPResponseStatusCode(rec.Code))
+			if rec.Code >= http.StatusInternalServerError {
+				span.SetStatus(codes.Error, fmt.Sprintf("status code %d", rec.Code))
+			}
+		})
+	}
+}
+
+// Fields returns the trace.id and span.id log fields of the span in the
+// context, or nothing if there is no span.
+func Fields(ctx context.Context) []zap.Field {
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.IsValid() {
+		return nil
+	}
+
+	return []zap.Field{
+		zap.String(log.TraceID, sc.TraceID().String()),
+		zap.String(log.SpanID, sc.SpanID().String()),
+	}
+}
@@ -0,0 +1,137 @@
+// SPDX-FileCopyrightText: 2024 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xmidt-org/sallust"
+	"github.com/xmidt-org/skeleton/internal/log"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+const (
+	traceID      = "4bf92f3577b34da6a3ce929d0e0e4736"
+	parentSpanID = "00f067aa0ba902b7"
+)
+
+func newTracing(t *testing.T) (*Tracing, *tracetest.InMemoryExporter) {
+	exporter := tracetest.NewInMemoryExporter()
+	tr, err := New(WithTracerProvider(
+		sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)),
+	))
+	require.NoError(t, err)
+	return tr, exporter
+}
+
+func attributes(span tracetest.SpanStub) map[attribute.Key]attribute.Value {
+	attrs := make(map[attribute.Key]attribute.Value)
+	for _, kv := range span.Attributes {
+		attrs[kv.Key] = kv.Value
+	}
+	return attrs
+}
+
+func TestMiddleware(t *testing.T) {
+	tr, exporter := newTracing(t)
+
+	var member string
+	mux := chi.NewMux()
+	mux.Get("/api/{version}/ok", func(w http.ResponseWriter, r *http.Request) {
+		member = baggage.FromContext(r.Context()).Member("tenant").Value()
+		w.WriteHeader(http.StatusAccepted)
+	})
+	mux.Get("/fail", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	})
+	h := tr.Middleware("primary")(mux)
+
+	req := httptest.NewRequest("GET", "/api/v1/ok", nil)
+	req.Header.Set("traceparent", "00-"+traceID+"-"+parentSpanID+"-01")
+	req.Header.Set("baggage", "tenant=acme")
+	resp := httptest.NewRecorder()
+	h.ServeHTTP(resp, req)
+	assert.Equal(t, http.StatusAccepted, resp.Code)
+	assert.Equal(t, "acme", member)
+
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 1)
+	span := spans[0]
+	assert.Equal(t, "GET /api/{version}/ok", span.Name)
+	assert.Equal(t, trace.SpanKindServer, span.SpanKind)
+	assert.Equal(t, traceID, span.SpanContext.TraceID().String())
+	assert.Equal(t, parentSpanID, span.Parent.SpanID().String())
+	assert.True(t, span.Parent.IsRemote())
+	assert.Equal(t, codes.Unset, span.Status.Code)
+
+	attrs := attributes(span)
+	assert.Equal(t, "primary", attrs[ServerKey].AsString())
+	assert.Equal(t, "/api/{version}/ok", attrs["http.route"].AsString())
+	assert.Equal(t, int64(http.StatusAccepted), attrs["http.response.status_code"].AsInt64())
+
+	exporter.Reset()
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/fail", nil))
+
+	spans = exporter.GetSpans()
+	require.Len(t, spans, 1)
+	assert.Equal(t, "GET /fail", spans[0].Name)
+	assert.False(t, spans[0].Parent.IsValid())
+	assert.Equal(t, codes.Error, spans[0].Status.Code)
+}
+
+func TestMiddlewareUnknownRoute(t *testing.T) {
+	tr, exporter := newTracing(t)
+	h := tr.Middleware("health")(chi.NewMux())
+
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/missing", nil))
+
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 1)
+	assert.Equal(t, "GET", spans[0].Name)
+	assert.Equal(t, int64(http.StatusNotFound), attributes(spans[0])["http.response.status_code"].AsInt64())
+}
+
+func TestRequestLogger(t *testing.T) {
+	core, logs := observer.New(zap.InfoLevel)
+	exporter := tracetest.NewInMemoryExporter()
+	tr, err := New(
+		WithLogger(zap.New(core)),
+		WithTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))),
+	)
+	require.NoError(t, err)
+
+	h := tr.Middleware("primary")(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		sallust.Get(r.Context()).Info("handled")
+	}))
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("traceparent", "00-"+traceID+"-"+parentSpanID+"-01")
+	h.ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 1)
+
+	entries := logs.FilterMessage("handled").All()
+	require.Len(t, entries, 1)
+	fields := entries[0].ContextMap()
+	assert.Equal(t, traceID, fields[log.TraceID])
+	assert.Equal(t, spans[0].SpanContext.SpanID().String(), fields[log.SpanID])
+}
+
+func TestFields(t *testing.T) {
+	assert.Empty(t, Fields(context.Background()))
+}
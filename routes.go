@@ -5,14 +5,21 @@ package skeleton
 
 import (
 	"net/http"
-	"strings"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/goschtalt/goschtalt"
 	"github.com/xmidt-org/arrange/arrangehttp"
 	"github.com/xmidt-org/arrange/arrangepprof"
-	"github.com/xmidt-org/httpaux"
+	"github.com/xmidt-org/skeleton/internal/accesslog"
 	"github.com/xmidt-org/skeleton/internal/apiauth"
+	"github.com/xmidt-org/skeleton/internal/cors"
+	"github.com/xmidt-org/skeleton/internal/health"
 	"github.com/xmidt-org/skeleton/internal/oker"
+	"github.com/xmidt-org/skeleton/internal/recovery"
+	"github.com/xmidt-org/skeleton/internal/requestid"
+	"github.com/xmidt-org/skeleton/internal/routes"
+	"github.com/xmidt-org/skeleton/internal/shutdown"
+	"github.com/xmidt-org/skeleton/internal/tracing"
 	"github.com/xmidt-org/touchstone/touchhttp"
 	"go.uber.org/fx"
 )
@@ -21,9 +28,12 @@ type RoutesIn struct {
 	fx.In
 	PrimaryMetrics   touchhttp.ServerInstrumenter `name:"servers.primary.metrics"`
 	AlternateMetrics touchhttp.ServerInstrumenter `name:"servers.alternate.metrics"`
-	Routes           Routes
-	Oker             *oker.Server
-	ApiAuth          *apiauth.Auth
+	Table            *routes.Table
+	Drainer          *shutdown.Drainer
+	Tracing          *tracing.Tracing
+	AccessLog        *accesslog.Logger
+	Recovery         *recovery.Recovery
+	RequestID        *requestid.Propagator
 }
 
 type RoutesOut struct {
@@ -32,70 +42,116 @@ type RoutesOut struct {
 	Alternate arrangehttp.Option[http.Server] `group:"servers.alternate.options"`
 }
 
-// The name should be 'primary' or 'alternate'.
 func provideCoreEndpoints() fx.Option {
-	return fx.Provide(
-		fx.Annotated{
-			Name: "servers.primary.metrics",
-			Target: touchhttp.ServerBundle{}.NewInstrumenter(
-				touchhttp.ServerLabel, "primary",
-			),
-		},
-		fx.Annotated{
-			Name: "servers.alternate.metrics",
-			Target: touchhttp.ServerBundle{}.NewInstrumenter(
-				touchhttp.ServerLabel, "alternate",
+	return fx.Options(
+		fx.Supply(
+			fx.Annotated{
+				Name:   "routes.servers",
+				Target: []string{"primary", "alternate"},
+			},
+		),
+		fx.Provide(
+			fx.Annotated{
+				Name: "servers.primary.metrics",
+				Target: touchhttp.ServerBundle{}.NewInstrumenter(
+					touchhttp.ServerLabel, "primary",
+				),
+			},
+			fx.Annotated{
+				Name: "servers.alternate.metrics",
+				Target: touchhttp.ServerBundle{}.NewInstrumenter(
+					touchhttp.ServerLabel, "alternate",
+				),
+			},
+			fx.Annotate(
+				func(o *oker.Server) routes.Handler {
+					return routes.Handler{Name: "oker", Handler: o}
+				},
+				fx.ResultTags(`group:"routes.handlers"`),
 			),
+			provideServerCORS("primary"),
+			provideServerCORS("alternate"),
+			func(in RoutesIn) RoutesOut {
+				return RoutesOut{
+					Primary:   provideCoreOption("primary", in.PrimaryMetrics, in),
+					Alternate: provideCoreOption("alternate", in.AlternateMetrics, in),
+				}
+			},
+		),
+	)
+}
+
+// provideServerCORS adds the CORS policy of the server to the route table.
+func provideServerCORS(server string) any {
+	return fx.Annotate(
+		func(gs *goschtalt.Config) (routes.CORS, error) {
+			c, err := goschtalt.Unmarshal[cors.Config](gs, "servers."+server+".cors", goschtalt.Optional())
+			return routes.CORS{Server: server, Config: c}, err
 		},
-		func(in RoutesIn) RoutesOut {
-			return RoutesOut{
-				Primary:   provideCoreOption("primary", in),
-				Alternate: provideCoreOption("alternate", in),
-			}
-		},
+		fx.ResultTags(`group:"routes.cors"`),
 	)
 }
 
-func provideCoreOption(server string, in RoutesIn) arrangehttp.Option[http.Server] {
+func provideCoreOption(server string, metrics touchhttp.ServerInstrumenter, in RoutesIn) arrangehttp.Option[http.Server] {
 	return arrangehttp.AsOption[http.Server](
 		func(s *http.Server) {
-			mux := chi.NewMux()
-			if strings.ToLower(in.Routes.Oker.Server) == server {
-				mux.Method("GET", in.Routes.Oker.Path,
-					in.ApiAuth.Then(in.Oker.ServeHTTP))
-			}
-			if server == "primary" {
-				s.Handler = in.PrimaryMetrics.Then(mux)
-			} else {
-				s.Handler = in.AlternateMetrics.Then(mux)
-			}
+			h := in.Recovery.Middleware(server)(in.Table.Handler(server))
+			h = metrics.Then(h)
+			h = in.AccessLog.Middleware(server)(h)
+			h = in.RequestID.Middleware(h)
+			h = in.Tracing.Middleware(server)(h)
+			s.Handler = in.Drainer.Track(server, s, h)
 		},
 	)
-
 }
 
 func provideHealthCheck() fx.Option {
-	return fx.Provide(
-		fx.Annotated{
-			Name: "servers.health.metrics",
-			Target: touchhttp.ServerBundle{}.NewInstrumenter(
-				touchhttp.ServerLabel, "health",
+	return fx.Options(
+		fx.Provide(
+			fx.Annotated{
+				Name: "servers.health.metrics",
+				Target: touchhttp.ServerBundle{}.NewInstrumenter(
+					touchhttp.ServerLabel, "health",
+				),
+			},
+			fx.Annotate(
+				func(metrics touchhttp.ServerInstrumenter, path HealthPath, registry *health.Registry, t *tracing.Tracing, a *accesslog.Logger, r *recovery.Recovery, ids *requestid.Propagator) arrangehttp.Option[http.Server] {
+					return arrangehttp.AsOption[http.Server](
+						func(s *http.Server) {
+							mux := chi.NewMux()
+							mux.Method("GET", "/livez", registry.Handler(health.Liveness))
+							mux.Method("GET", "/readyz", registry.Handler(health.Readiness))
+							mux.Method("GET", "/startupz", registry.Handler(health.Startup))
+							mux.Method("GET", string(path), registry.Handler(health.Readiness))
+							h := metrics.Then(r.Middleware("health")(mux))
+							h = a.Middleware("health")(h)
+							h = ids.Middleware(h)
+							s.Handler = t.Middleware("health")(h)
+						},
+					)
+				},
+				fx.ParamTags(`name:"servers.health.metrics"`),
+				fx.ResultTags(`group:"servers.health.options"`),
 			),
-		},
+		),
+		provideListenerCheck("servers.primary"),
+		provideListenerCheck("servers.alternate"),
+	)
+}
+
+// provideListenerCheck adds a health check that the server is accepting
+// connections.
+func provideListenerCheck(serverName string) fx.Option {
+	return fx.Provide(
 		fx.Annotate(
-			func(metrics touchhttp.ServerInstrumenter, path HealthPath) arrangehttp.Option[http.Server] {
-				return arrangehttp.AsOption[http.Server](
-					func(s *http.Server) {
-						mux := chi.NewMux()
-						mux.Method("GET", string(path), httpaux.ConstantHandler{
-							StatusCode: http.StatusOK,
-						})
-						s.Handler = metrics.Then(mux)
-					},
-				)
+			func() (arrangehttp.ListenerMiddleware, health.Check) {
+				l := health.NewListener(serverName)
+				return l.Middleware, l.Check()
 			},
-			fx.ParamTags(`name:"servers.health.metrics"`),
-			fx.ResultTags(`group:"servers.health.options"`),
+			fx.ResultTags(
+				`group:"`+serverName+`.listener.middleware"`,
+				`group:"health.checks"`,
+			),
 		),
 	)
 }
@@ -103,12 +159,13 @@ func provideHealthCheck() fx.Option {
 func provideMetricEndpoint() fx.Option {
 	return fx.Provide(
 		fx.Annotate(
-			func(metrics touchhttp.Handler, path MetricsPath) arrangehttp.Option[http.Server] {
+			func(metrics touchhttp.Handler, path MetricsPath, a *accesslog.Logger, r *recovery.Recovery, ids *requestid.Propagator) arrangehttp.Option[http.Server] {
 				return arrangehttp.AsOption[http.Server](
 					func(s *http.Server) {
 						mux := chi.NewMux()
 						mux.Method("GET", string(path), metrics)
-						s.Handler = mux
+						h := a.Middleware("metrics")(r.Middleware("metrics")(mux))
+						s.Handler = ids.Middleware(h)
 					},
 				)
 			},
@@ -117,19 +174,46 @@ func provideMetricEndpoint() fx.Option {
 	)
 }
 
+type pprofIn struct {
+	fx.In
+	PathPrefix   PprofPathPrefix
+	RecentEvents RecentEventsPath
+	EventStream  EventStreamPath
+	Oker         *oker.Server
+	ApiAuth      *apiauth.Auth
+	AccessLog    *accesslog.Logger
+	Recovery     *recovery.Recovery
+	RequestID    *requestid.Propagator
+}
+
 func providePprofEndpoint() fx.Option {
 	return fx.Provide(
 		fx.Annotate(
-			func(pathPrefix PprofPathPrefix) arrangehttp.Option[http.Server] {
-				return arrangehttp.AsOption[http.Server](
-					func(s *http.Server) {
-						s.Handler = arrangepprof.HTTP{
-							PathPrefix: string(pathPrefix),
-						}.New()
-					},
-				)
-			},
+			providePprofOption,
 			fx.ResultTags(`group:"servers.pprof.options"`),
 		),
 	)
 }
+
+func providePprofOption(in pprofIn) arrangehttp.Option[http.Server] {
+	return arrangehttp.AsOption[http.Server](
+		func(s *http.Server) {
+			mux := arrangepprof.HTTP{
+				PathPrefix: string(in.PathPrefix),
+			}.New()
+			if recent := in.Oker.RecentEvents(); recent != nil && in.RecentEvents != "" {
+				mux.Handle("GET "+string(in.RecentEvents), in.ApiAuth.Then(recent.ServeHTTP))
+			}
+			if stream := in.Oker.EventStream(); stream != nil && in.EventStream != "" {
+				mux.Handle("GET "+string(in.EventStream), in.ApiAuth.Then(stream.ServeHTTP))
+
+				// The stream connections never go idle, so they are closed
+				// before the server waits on them.
+				s.RegisterOnShutdown(stream.Close)
+			}
+			h := in.Recovery.Middleware("pprof")(mux)
+			h = in.AccessLog.Middleware("pprof")(h)
+			s.Handler = in.RequestID.Middleware(h)
+		},
+	)
+}
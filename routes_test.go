@@ -0,0 +1,188 @@
+// SPDX-FileCopyrightText: 2024 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package skeleton
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"syscall"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xmidt-org/skeleton/internal/accesslog"
+	"github.com/xmidt-org/skeleton/internal/apiauth"
+	"github.com/xmidt-org/skeleton/internal/log"
+	"github.com/xmidt-org/skeleton/internal/oker"
+	"github.com/xmidt-org/skeleton/internal/recovery"
+	"github.com/xmidt-org/skeleton/internal/requestid"
+	"github.com/xmidt-org/skeleton/internal/routes"
+	"github.com/xmidt-org/skeleton/internal/shutdown"
+	"github.com/xmidt-org/skeleton/internal/tracing"
+	"github.com/xmidt-org/touchstone"
+	"github.com/xmidt-org/touchstone/touchhttp"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// newPrimaryServer starts a server with the handler chain of the primary
+// server, serving oker at /ok.  The middleware log to the logger, as they do
+// with the application logger.
+func newPrimaryServer(t *testing.T, logger *zap.Logger, ids requestid.Config) *httptest.Server {
+	o, err := oker.New(oker.WithFaultHeader(true))
+	require.NoError(t, err)
+
+	table, err := routes.New(
+		routes.WithConfig(routes.Config{
+			"oker": {Path: "/ok", Servers: []string{"primary"}, Auth: routes.AuthNone},
+		}),
+		routes.WithServers("primary"),
+		routes.WithHandlers(routes.Handler{Name: "oker", Handler: o}),
+	)
+	require.NoError(t, err)
+
+	f := touchstone.NewFactory(touchstone.Config{}, zap.NewNop(), prometheus.NewRegistry())
+	metrics, err := touchhttp.ServerBundle{}.NewInstrumenter(touchhttp.ServerLabel, "primary")(f)
+	require.NoError(t, err)
+
+	in := RoutesIn{
+		PrimaryMetrics: metrics,
+		Table:          table,
+	}
+	in.Drainer, err = shutdown.New()
+	require.NoError(t, err)
+	in.Tracing, err = tracing.New(tracing.WithLogger(logger))
+	require.NoError(t, err)
+	in.AccessLog, err = accesslog.New(accesslog.WithLogger(logger))
+	require.NoError(t, err)
+	in.Recovery, err = recovery.New()
+	require.NoError(t, err)
+	in.RequestID, err = requestid.New(requestid.WithConfig(ids), requestid.WithLogger(logger))
+	require.NoError(t, err)
+
+	var s http.Server
+	require.NoError(t, provideCoreOption("primary", metrics, in).Apply(&s))
+
+	server := httptest.NewServer(s.Handler)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestCoreChain(t *testing.T) {
+	server := newPrimaryServer(t, zap.NewNop(), requestid.Config{})
+
+	resp, err := http.Get(server.URL + "/ok")
+	require.NoError(t, err)
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.NotEmpty(t, body)
+	assert.NotEmpty(t, resp.Header.Get(requestid.DefaultHeader))
+}
+
+func TestCoreChainRequestID(t *testing.T) {
+	core, logs := observer.New(zap.InfoLevel)
+	server := newPrimaryServer(t, zap.New(core), requestid.Config{
+		Trusted: []string{"127.0.0.0/8", "::1/128"},
+	})
+
+	tests := []struct {
+		name string
+		id   string
+	}{
+		{name: "generated"},
+		{name: "propagated", id: "abc123"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			logs.TakeAll()
+
+			req, err := http.NewRequest("GET", server.URL+"/ok", nil)
+			require.NoError(t, err)
+			if tc.id != "" {
+				req.Header.Set(requestid.DefaultHeader, tc.id)
+			}
+
+			resp, err := http.DefaultClient.Do(req)
+			require.NoError(t, err)
+			resp.Body.Close()
+
+			id := resp.Header.Get(requestid.DefaultHeader)
+			require.NotEmpty(t, id)
+			if tc.id != "" {
+				assert.Equal(t, tc.id, id)
+			}
+
+			entries := logs.FilterMessage(accesslog.Message).All()
+			require.Len(t, entries, 1)
+			assert.Equal(t, id, entries[0].ContextMap()[log.HTTPRequestID])
+		})
+	}
+}
+
+func TestCoreChainReset(t *testing.T) {
+	server := newPrimaryServer(t, zap.NewNop(), requestid.Config{})
+
+	// The reset fault hijacks the connection, which every writer wrapped
+	// around the handler has to allow.  Without it the request is aborted,
+	// which closes the connection without the reset.
+	req, err := http.NewRequest("GET", server.URL+"/ok", nil)
+	require.NoError(t, err)
+	req.Header.Set(oker.FaultHeader, "reset")
+
+	client := http.Client{Transport: &http.Transport{DisableKeepAlives: true}}
+	resp, err := client.Do(req)
+	if err == nil {
+		resp.Body.Close()
+	}
+	assert.ErrorIs(t, err, syscall.ECONNRESET)
+}
+
+func TestPprofEventsAuth(t *testing.T) {
+	o, err := oker.New(oker.WithConfig(oker.Config{
+		Recent: oker.Recent{Capacity: 10},
+	}))
+	require.NoError(t, err)
+
+	in := pprofIn{
+		PathPrefix:   "/debug/pprof",
+		RecentEvents: "/debug/oker/events",
+		Oker:         o,
+	}
+	in.ApiAuth, err = apiauth.New(apiauth.WithConfig(apiauth.Config{
+		Basic: apiauth.Basic{"user": "pass"},
+	}))
+	require.NoError(t, err)
+	in.AccessLog, err = accesslog.New()
+	require.NoError(t, err)
+	in.Recovery, err = recovery.New()
+	require.NoError(t, err)
+	in.RequestID, err = requestid.New()
+	require.NoError(t, err)
+
+	var s http.Server
+	require.NoError(t, providePprofOption(in).Apply(&s))
+
+	server := httptest.NewServer(s.Handler)
+	defer server.Close()
+
+	get := func(user, password string) int {
+		req, err := http.NewRequest("GET", server.URL+"/debug/oker/events", nil)
+		require.NoError(t, err)
+		if user != "" {
+			req.SetBasicAuth(user, password)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	assert.Equal(t, http.StatusUnauthorized, get("", ""))
+	assert.Equal(t, http.StatusUnauthorized, get("user", "wrong"))
+	assert.Equal(t, http.StatusOK, get("user", "pass"))
+}
@@ -6,6 +6,7 @@ package skeleton
 import (
 	"fmt"
 	"os"
+	"time"
 
 	"github.com/alecthomas/kong"
 	"github.com/goschtalt/goschtalt"
@@ -15,9 +16,17 @@ import (
 	"github.com/xmidt-org/arrange/arrangehttp"
 	"github.com/xmidt-org/candlelight"
 	"github.com/xmidt-org/sallust"
+	"github.com/xmidt-org/skeleton/internal/accesslog"
 	"github.com/xmidt-org/skeleton/internal/apiauth"
+	"github.com/xmidt-org/skeleton/internal/credentials"
+	"github.com/xmidt-org/skeleton/internal/health"
 	"github.com/xmidt-org/skeleton/internal/metrics"
 	"github.com/xmidt-org/skeleton/internal/oker"
+	"github.com/xmidt-org/skeleton/internal/recovery"
+	"github.com/xmidt-org/skeleton/internal/requestid"
+	"github.com/xmidt-org/skeleton/internal/routes"
+	"github.com/xmidt-org/skeleton/internal/shutdown"
+	"github.com/xmidt-org/skeleton/internal/tracing"
 	"github.com/xmidt-org/touchstone"
 	"github.com/xmidt-org/touchstone/touchhttp"
 
@@ -29,6 +38,10 @@ import (
 const (
 	applicationNamespace = "xmidt"
 	applicationName      = "skeleton"
+
+	// stopTimeout bounds the whole shutdown.  Configs whose drain and
+	// shutdown timeouts don't fit in it fail to start.
+	stopTimeout = time.Minute
 )
 
 // These match what goreleaser provides.
@@ -60,6 +73,12 @@ func Main(args []string, run bool) error { // nolint: funlen
 
 	app := fx.New(
 		fx.Supply(cliArgs(args)),
+		fx.Supply(oker.BuildInfo{
+			Version: version,
+			Commit:  commit,
+			Date:    date,
+			BuiltBy: builtBy,
+		}),
 		fx.Populate(&g),
 		fx.Populate(&gscfg),
 		fx.Populate(&cli),
@@ -71,6 +90,9 @@ func Main(args []string, run bool) error { // nolint: funlen
 		fx.Provide(
 			provideCLI,
 			provideLogger,
+			func(cli *CLI) oker.DevMode {
+				return oker.DevMode(cli.Dev)
+			},
 			provideConfig,
 			goschtalt.UnmarshalFunc[sallust.Config]("logging"),
 			goschtalt.UnmarshalFunc[candlelight.Config]("tracing"),
@@ -79,9 +101,16 @@ func Main(args []string, run bool) error { // nolint: funlen
 			goschtalt.UnmarshalFunc[HealthPath]("servers.health.path", goschtalt.Optional()),
 			goschtalt.UnmarshalFunc[MetricsPath]("servers.metrics.path", goschtalt.Optional()),
 			goschtalt.UnmarshalFunc[PprofPathPrefix]("servers.pprof.path", goschtalt.Optional()),
-			goschtalt.UnmarshalFunc[Routes]("routes"),
+			goschtalt.UnmarshalFunc[RecentEventsPath]("servers.pprof.recent_events", goschtalt.Optional()),
+			goschtalt.UnmarshalFunc[EventStreamPath]("servers.pprof.event_stream", goschtalt.Optional()),
+			goschtalt.UnmarshalFunc[accesslog.Config]("access_log", goschtalt.Optional()),
+			goschtalt.UnmarshalFunc[health.Config]("health", goschtalt.Optional()),
+			goschtalt.UnmarshalFunc[requestid.Config]("request_id", goschtalt.Optional()),
+			goschtalt.UnmarshalFunc[shutdown.Config]("shutdown", goschtalt.Optional()),
+			goschtalt.UnmarshalFunc[routes.Config]("routes"),
 			goschtalt.UnmarshalFunc[oker.Config]("oker"),
 			goschtalt.UnmarshalFunc[apiauth.Config]("auth", goschtalt.Optional()),
+			goschtalt.UnmarshalFunc[credentials.Config]("credentials", goschtalt.Optional()),
 			// fx.Annotated{
 			// 	Name:   "tracing_initial_config",
 			// 	Target: goschtalt.UnmarshalFunc[candlelight.Config]("tracing"),
@@ -121,8 +150,20 @@ func Main(args []string, run bool) error { // nolint: funlen
 		arrangehttp.ProvideServer("servers.primary"),
 		arrangehttp.ProvideServer("servers.alternate"),
 
+		// The drain has to stop before the servers, so it comes after them.
+		shutdown.Hook(stopTimeout),
+
+		accesslog.Module,
 		apiauth.Module,
+		credentials.Module,
+		credentials.ProvideClientOption("oker.webhooks"),
+		health.Module,
 		oker.Module,
+		recovery.Module,
+		requestid.Module,
+		routes.Module,
+		shutdown.Module,
+		tracing.Module,
 		touchstone.Provide(),
 		touchhttp.Provide(),
 		metrics.Provide(),